@@ -0,0 +1,208 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// AppTokenSource a token source which authenticates as a GitHub App: it signs a JWT with the private key of the app,
+// exchanges it for an installation token for each owner (organization or user) and caches the installation tokens until they expire.
+// see https://developer.github.com/apps/building-github-apps/authenticating-with-github-apps/
+type AppTokenSource struct {
+	appID      int64
+	key        *rsa.PrivateKey
+	baseURL    string
+	httpClient *http.Client
+	lock       sync.Mutex
+	tokens     map[string]installationToken
+	// the locks held while obtaining the installation token of each owner
+	ownerLocks map[string]*sync.Mutex
+}
+
+// an installation token along with its expiry time
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+const (
+	// the media type required to access the GitHub Apps endpoints
+	appMediaType = "application/vnd.github.machine-man-preview+json"
+	// the margin before the expiry time after which an installation token is refreshed
+	tokenExpiryMargin = time.Minute
+)
+
+// NewAppTokenSource returns a new token source for the GitHub App with the given ID, using the private key in the given PEM file.
+// The installation tokens are obtained on the Rest API at the given base URL (or the default URL if empty), using the given HTTP client
+// (or `http.DefaultClient` if nil).
+func NewAppTokenSource(appID int64, privateKeyFile, baseURL string, httpClient *http.Client) (*AppTokenSource, error) {
+	data, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the GitHub App private key")
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the GitHub App private key in '%s'", privateKeyFile)
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &AppTokenSource{
+		appID:      appID,
+		key:        key,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		tokens:     map[string]installationToken{},
+		ownerLocks: map[string]*sync.Mutex{},
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+// Token returns the token of the app installation for the given owner, which is obtained
+// (or refreshed) if there is no cached token, or if the cached token is about to expire
+func (s *AppTokenSource) Token(ctx context.Context, owner string) (string, error) {
+	if owner == "" {
+		return "", errors.New("unable to obtain a GitHub App installation token: unknown repository owner")
+	}
+	// only the requests for the same owner wait for the installation token being obtained
+	ownerLock := s.ownerLock(owner)
+	ownerLock.Lock()
+	defer ownerLock.Unlock()
+	if t, found := s.cachedToken(owner); found && time.Now().Add(tokenExpiryMargin).Before(t.ExpiresAt) {
+		return t.Token, nil
+	}
+	jwt, err := s.jwt()
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to sign the GitHub App JWT")
+	}
+	installationID, err := s.installationID(ctx, jwt, owner)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find the GitHub App installation for '%s'", owner)
+	}
+	t := installationToken{}
+	err = s.execute(ctx, jwt, "POST", fmt.Sprintf("%s/app/installations/%d/access_tokens", s.baseURL, installationID), &t)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to obtain a GitHub App installation token for '%s'", owner)
+	}
+	log.Debugf("obtained GitHub App installation token for '%s' (expires at %s)", owner, t.ExpiresAt.Format(time.RFC3339))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens[owner] = t
+	return t.Token, nil
+}
+
+// ownerLock returns the lock to hold while obtaining the installation token for the given owner
+func (s *AppTokenSource) ownerLock(owner string) *sync.Mutex {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	l, found := s.ownerLocks[owner]
+	if !found {
+		l = &sync.Mutex{}
+		s.ownerLocks[owner] = l
+	}
+	return l
+}
+
+// cachedToken returns the cached installation token for the given owner, if any
+func (s *AppTokenSource) cachedToken(owner string) (installationToken, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, found := s.tokens[owner]
+	return t, found
+}
+
+// installationID returns the ID of the app installation on the given organization or user account
+func (s *AppTokenSource) installationID(ctx context.Context, jwt, owner string) (int64, error) {
+	installation := struct {
+		ID int64 `json:"id"`
+	}{}
+	err := s.execute(ctx, jwt, "GET", fmt.Sprintf("%s/orgs/%s/installation", s.baseURL, owner), &installation)
+	if _, ok := err.(NotFoundError); ok {
+		// not an organization, maybe a user account
+		err = s.execute(ctx, jwt, "GET", fmt.Sprintf("%s/users/%s/installation", s.baseURL, owner), &installation)
+	}
+	return installation.ID, err
+}
+
+// jwt returns a new JWT signed with the private key of the app, valid for 10 minutes (the maximum allowed)
+func (s *AppTokenSource) jwt() (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(), // allow some clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *AppTokenSource) execute(ctx context.Context, jwt, method, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "unable to execute HTTP request")
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	req.Header.Add("Accept", appMediaType)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "unable to execute HTTP request")
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "unable to execute HTTP request")
+	}
+	if resp.StatusCode >= 300 {
+		return newResponseError(resp, body)
+	}
+	return json.Unmarshal(body, result)
+}
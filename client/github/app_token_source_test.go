@@ -0,0 +1,134 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/testsupport"
+)
+
+// newAppServer starts a server which serves the GitHub Apps endpoints for the installations on the given owners
+// (with the `installation-<owner>` tokens), and delegates the other requests to the given fake GitHub server
+// after checking their token. The optional `installing` func is called before replying with an installation.
+func newAppServer(t *testing.T, gh *testsupport.GitHubServer, owners []string, installing func(owner string)) *httptest.Server {
+	mux := http.NewServeMux()
+	for i, owner := range owners {
+		id, owner := i+1, owner
+		mux.HandleFunc(fmt.Sprintf("/orgs/%s/installation", owner), func(w http.ResponseWriter, req *http.Request) {
+			if installing != nil {
+				installing(owner)
+			}
+			fmt.Fprintf(w, `{"id": %d}`, id)
+		})
+		mux.HandleFunc(fmt.Sprintf("/app/installations/%d/access_tokens", id), func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, `{"token": "installation-%s", "expires_at": "%s"}`, owner, time.Now().Add(time.Hour).Format(time.RFC3339))
+		})
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer installation-") {
+			http.Error(w, fmt.Sprintf(`{"message": "Bad credentials: '%s'"}`, auth), http.StatusUnauthorized)
+			return
+		}
+		gh.Config.Handler.ServeHTTP(w, req)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestAppTokenSource returns a token source with a new private key, for the app at the given URL
+func newTestAppTokenSource(t *testing.T, baseURL string) *AppTokenSource {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(keyFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := NewAppTokenSource(1, keyFile, baseURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestListMilestonesOnSeveralPagesWithAppToken(t *testing.T) {
+	// given more milestones than fit in a single page
+	gh := testsupport.NewGitHubServer()
+	defer gh.Close()
+	a := gh.AddRepository("o/a", 1)
+	for i := 1; i <= perPage+10; i++ {
+		a.AddMilestone(fmt.Sprintf("Sprint %d", i), time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	}
+	server := newAppServer(t, gh, []string{"o"}, nil)
+	c := NewClient(Config{
+		BaseURL: server.URL,
+		Tokens:  newTestAppTokenSource(t, server.URL),
+	})
+	// when
+	milestones, err := c.ListMilestones(context.Background(), "o/a")
+	// then the next page (at '/repositories/1/milestones') was fetched with the installation token of the owner
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(milestones) != perPage+10 {
+		t.Fatalf("expected %d milestones, got %d", perPage+10, len(milestones))
+	}
+}
+
+func TestTokenIsNotBlockedByOtherOwner(t *testing.T) {
+	// given an installation which takes a while to be found
+	gh := testsupport.NewGitHubServer()
+	defer gh.Close()
+	installing, release := make(chan struct{}), make(chan struct{})
+	server := newAppServer(t, gh, []string{"slow", "o"}, func(owner string) {
+		if owner == "slow" {
+			close(installing)
+			<-release
+		}
+	})
+	tokens := newTestAppTokenSource(t, server.URL)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tokens.Token(context.Background(), "slow")
+	}()
+	defer func() {
+		close(release)
+		wg.Wait()
+	}()
+	<-installing
+	// when
+	done := make(chan error, 1)
+	go func() {
+		token, err := tokens.Token(context.Background(), "o")
+		if err == nil && token != "installation-o" {
+			err = fmt.Errorf("unexpected token '%s'", token)
+		}
+		done <- err
+	}()
+	// then the token of the other owner is obtained in the meantime
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the token was not obtained while the installation of another owner was being found")
+	}
+}
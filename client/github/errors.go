@@ -0,0 +1,128 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NotFoundError the error returned when a resource (eg: a repository) could not be found
+type NotFoundError struct {
+	Message string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("not found: %s", e.Message)
+}
+
+// MilestoneNotFoundError the error returned when a repository has no milestone with a given title
+// (as opposed to a `NotFoundError` when the repository itself does not exist)
+type MilestoneNotFoundError struct {
+	Repository string
+	Title      string
+}
+
+func (e MilestoneNotFoundError) Error() string {
+	return fmt.Sprintf("unable to find milestone with title '%s' in repository '%s'", e.Title, e.Repository)
+}
+
+// UnauthorizedError the error returned when the request could not be authenticated (eg: invalid or expired token)
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized: %s", e.Message)
+}
+
+// ForbiddenError the error returned when the token does not grant access to a resource (eg: missing scope)
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return fmt.Sprintf("forbidden: %s", e.Message)
+}
+
+// FieldError the details of a validation error on a given field of a resource
+// see https://developer.github.com/v3/#client-errors
+type FieldError struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	// Code the error code: 'missing', 'missing_field', 'invalid', 'already_exists' or 'custom'
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// ValidationError the error returned when the payload of the request was rejected
+type ValidationError struct {
+	Message string
+	Errors  []FieldError
+}
+
+func (e ValidationError) Error() string {
+	details := make([]string, len(e.Errors))
+	for i, f := range e.Errors {
+		details[i] = fmt.Sprintf("%s.%s: %s", f.Resource, f.Field, f.Code)
+		if f.Message != "" {
+			details[i] = fmt.Sprintf("%s (%s)", details[i], f.Message)
+		}
+	}
+	return fmt.Sprintf("validation failed: %s [%s]", e.Message, strings.Join(details, ", "))
+}
+
+// HasCode returns true if any of the field errors has the given code (eg: 'already_exists')
+func (e ValidationError) HasCode(code string) bool {
+	for _, f := range e.Errors {
+		if f.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitError the error returned when the rate-limit budget is exhausted
+type RateLimitError struct {
+	Message string
+	// Reset the time at which the budget will be reset (zero if unknown)
+	Reset time.Time
+}
+
+func (e RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("rate limit exceeded: %s", e.Message)
+	}
+	return fmt.Sprintf("rate limit exceeded until %s: %s", e.Reset.Format(time.RFC3339), e.Message)
+}
+
+// newResponseError converts the given error response of the Rest API into a typed error, when applicable
+func newResponseError(resp *http.Response, body []byte) error {
+	payload := struct {
+		Message string       `json:"message"`
+		Errors  []FieldError `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Message == "" {
+		payload.Message = string(body)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return UnauthorizedError{Message: payload.Message}
+	case http.StatusForbidden:
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" || strings.Contains(strings.ToLower(payload.Message), "rate limit") {
+			return RateLimitError{Message: payload.Message, Reset: resetTime(resp.Header)}
+		}
+		return ForbiddenError{Message: payload.Message}
+	case http.StatusTooManyRequests:
+		return RateLimitError{Message: payload.Message, Reset: resetTime(resp.Header)}
+	case http.StatusNotFound:
+		return NotFoundError{Message: payload.Message}
+	case http.StatusUnprocessableEntity:
+		return ValidationError{Message: payload.Message, Errors: payload.Errors}
+	default:
+		return errors.Errorf("failed to execute query: %d, %s", resp.StatusCode, string(body))
+	}
+}
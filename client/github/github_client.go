@@ -2,12 +2,13 @@ package github
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +16,74 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// DefaultBaseURL the default base URL of the GitHub Rest v3 API
+	DefaultBaseURL = "https://api.github.com"
+	// DefaultGraphqlURL the default URL of the GitHub GraphQL API
+	DefaultGraphqlURL = "https://api.github.com/graphql"
+)
+
+// TokenSource provides the token to authenticate the requests on the GitHub API
+type TokenSource interface {
+	// Token returns the token to access the repositories of the given owner (organization or user)
+	Token(ctx context.Context, owner string) (string, error)
+}
+
+// StaticTokenSource a token source which always returns the same token, regardless of the owner
+type StaticTokenSource string
+
+// Token returns the static token
+func (s StaticTokenSource) Token(ctx context.Context, owner string) (string, error) {
+	return string(s), nil
+}
+
+// Config the configuration of the GitHub client
+type Config struct {
+	// BaseURL the base URL of the Rest v3 API (eg: 'https://github.example.com/api/v3' for GitHub Enterprise)
+	BaseURL string
+	// GraphqlURL the URL of the GraphQL API (eg: 'https://github.example.com/api/graphql' for GitHub Enterprise)
+	GraphqlURL string
+	// HTTPClient the underlying HTTP client. Uses `http.DefaultClient` if nil
+	HTTPClient *http.Client
+	// Tokens the source of tokens to authenticate the requests
+	Tokens TokenSource
+	// MaxPages the maximum number of pages to fetch on list endpoints (no limit if 0)
+	MaxPages int
+}
+
+// Client the client for the GitHub Rest v3 and GraphQL APIs
+type Client struct {
+	baseURL    string
+	graphqlURL string
+	httpClient *http.Client
+	tokens     TokenSource
+	maxPages   int
+}
+
+// NewClient returns a new client configured with the given config
+func NewClient(config Config) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
+		graphqlURL: config.GraphqlURL,
+		httpClient: config.HTTPClient,
+		tokens:     config.Tokens,
+		maxPages:   config.MaxPages,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.graphqlURL == "" {
+		c.graphqlURL = DefaultGraphqlURL
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.tokens == nil {
+		c.tokens = StaticTokenSource("")
+	}
+	return c
+}
+
 // Milestone data for a milestone
 type Milestone struct {
 	Number int64  `json:"number"`
@@ -32,14 +101,49 @@ type Issue struct {
 	Milestone Milestone `json:"milestone"`
 }
 
-// ExecuteGraphqlQuery executes the given GraphQL query on the GitHub API endpoint
-func ExecuteGraphqlQuery(query string, result interface{}) error {
-	query = strings.Replace(strings.Replace(query, "\n", " ", -1), "\t", "", -1)
-	return execute("POST", "https://api.github.com/graphql", bytes.NewReader([]byte(query)), result)
+// ExecuteGraphqlQuery executes the given GraphQL request on the GitHub API endpoint and decodes the `data`
+// of the response in the given result. If the response contains errors, then a `GraphqlErrors` is returned,
+// along with the partial data decoded in the result.
+func (c *Client) ExecuteGraphqlQuery(ctx context.Context, request GraphqlRequest, result interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrapf(err, "unable to encode GraphQL request")
+	}
+	body, header, err := c.doAs(ctx, request.Owner, "POST", c.graphqlURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	response := graphqlResponse{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return errors.Wrapf(err, "unable to decode GraphQL response")
+	}
+	if len(response.Data) > 0 && string(response.Data) != "null" {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return errors.Wrapf(err, "unable to decode GraphQL response data")
+		}
+	}
+	if len(response.Errors) > 0 {
+		for _, e := range response.Errors {
+			if e.Type == "RATE_LIMITED" {
+				return RateLimitError{Message: e.Error(), Reset: resetTime(header)}
+			}
+		}
+		return response.Errors
+	}
+	return nil
+}
+
+// resetTime returns the time at which the rate-limit budget will be reset, or a zero time if unknown
+func resetTime(header http.Header) time.Time {
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(reset, 0)
 }
 
 // CreateMilestone creates a new milestone (using the Rest v3 API)
-func CreateMilestone(repo, name string, endDate time.Time) (Milestone, error) {
+func (c *Client) CreateMilestone(ctx context.Context, repo, name string, endDate time.Time) (Milestone, error) {
 	// curl -X POST https://api.github.com/repos/fabric8-services/fabric8-tenant/milestones
 	// -H "Authorization: Bearer $GITHUB_TOKEN"
 	// -d '{
@@ -49,28 +153,31 @@ func CreateMilestone(repo, name string, endDate time.Time) (Milestone, error) {
 	//   }'
 
 	result := Milestone{}
-	url := fmt.Sprintf("https://api.github.com/repos/%s/milestones", repo)
-	payload := fmt.Sprintf(`{
-		"title": "%s",
-		"state": "open",
-		"due_on": "%s"
-	}`, name, endDate.Format("2006-01-02T00:00:00Z"))
-	err := execute("POST", url, bytes.NewReader([]byte(payload)), &result)
+	url := fmt.Sprintf("%s/repos/%s/milestones", c.baseURL, repo)
+	payload, err := json.Marshal(map[string]string{
+		"title":  name,
+		"state":  "open",
+		"due_on": endDate.Format("2006-01-02T00:00:00Z"),
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to encode milestone")
+	}
+	err = c.execute(ctx, "POST", url, bytes.NewReader(payload), &result)
 	return result, err
 }
 
 // CloseMilestone closes the given milestone
-func CloseMilestone(milestone *Milestone) error {
+func (c *Client) CloseMilestone(ctx context.Context, milestone *Milestone) error {
 	// see https://developer.github.com/v3/issues/milestones/#update-a-milestone
 	// PATCH /repos/:owner/:repo/milestones/:number
 	// state: closed
 	payload := `{"state":"closed"}`
-	return execute("PATCH", milestone.URL, bytes.NewReader([]byte(payload)), milestone)
+	return c.execute(ctx, "PATCH", milestone.URL, bytes.NewReader([]byte(payload)), milestone)
 }
 
-// FetchMilestone fetches the open milestone with the given title
-func FetchMilestone(repo, title string) (Milestone, error) {
-	milestones, err := ListMilestones(repo)
+// FetchMilestone fetches the milestone with the given title, or returns a `MilestoneNotFoundError` if none matched
+func (c *Client) FetchMilestone(ctx context.Context, repo, title string) (Milestone, error) {
+	milestones, err := c.ListMilestones(ctx, repo)
 	if err != nil {
 		return Milestone{}, errors.Wrapf(err, "failed to retrieve milestones for repository '%s'", repo)
 	}
@@ -79,56 +186,149 @@ func FetchMilestone(repo, title string) (Milestone, error) {
 			return m, nil
 		}
 	}
-	return Milestone{}, errors.Errorf("unable to find milestone with title '%s' in repository '%s'", title, repo)
+	return Milestone{}, MilestoneNotFoundError{Repository: repo, Title: title}
 }
 
 // FetchMilestoneIssues fetches all open issues for the milestone given its number, on the given repository
-func FetchMilestoneIssues(repo string, number int64) ([]Issue, error) {
+func (c *Client) FetchMilestoneIssues(ctx context.Context, repo string, number int64) ([]Issue, error) {
 	// see https://developer.github.com/v3/issues/#list-issues-for-a-repository to retrieve all open issues for the given milestone (using its name)
 	// e.g.: curl https://api.github.com/repos/fabric8-services/fabric8-cluster/issues?milestone=3
 	result := []Issue{}
-	url := fmt.Sprintf("https://api.github.com/repos/%s/issues?state=open&milestone=%d", repo, number)
-	err := execute("GET", url, nil, &result)
+	url := fmt.Sprintf("%s/repos/%s/issues?state=open&milestone=%d", c.baseURL, repo, number)
+	err := c.paginate(ctx, url, func(body []byte) error {
+		page := []Issue{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		result = append(result, page...)
+		return nil
+	})
 	return result, err
 }
 
 // MoveIssue moves the given issue to the given milestone
-func MoveIssue(issue *Issue, milestone Milestone) error {
+func (c *Client) MoveIssue(ctx context.Context, issue *Issue, milestone Milestone) error {
 	// see https://developer.github.com/v3/issues/#edit-an-issue to change the milestone
 	// PATCH /repos/:owner/:repo/issues/:number
 	// milestone: integer
 	payload := fmt.Sprintf(`{"milestone":%d}`, milestone.Number)
-	return execute("PATCH", issue.URL, bytes.NewReader([]byte(payload)), issue)
+	return c.execute(ctx, "PATCH", issue.URL, bytes.NewReader([]byte(payload)), issue)
 }
 
 // ListMilestones lists *all* milestones for the given repo (using the Rest v3 API)
-func ListMilestones(repo string) ([]Milestone, error) {
+func (c *Client) ListMilestones(ctx context.Context, repo string) ([]Milestone, error) {
 	// see https://developer.github.com/v3/issues/milestones/#list-milestones-for-a-repository
 	// e.g.: curl https://api.github.com/repos/fabric8-services/fabric8-cluster/milestones
 	result := []Milestone{}
-	url := fmt.Sprintf("https://api.github.com/repos/%s/milestones?state=all&direction=desc", repo)
-	err := execute("GET", url, nil, &result)
+	url := fmt.Sprintf("%s/repos/%s/milestones?state=all&direction=desc", c.baseURL, repo)
+	err := c.paginate(ctx, url, func(body []byte) error {
+		page := []Milestone{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		result = append(result, page...)
+		return nil
+	})
 	return result, err
 }
 
-func execute(method, url string, payload io.Reader, result interface{}) error {
-	req, err := http.NewRequest(method, url, payload)
+// the number of items per page on list endpoints (maximum allowed by GitHub)
+const perPage = 100
+
+// paginate fetches all pages of the list at the given URL, following the `Link: <...>; rel="next"` response headers
+// until the last page (or until the maximum number of pages is reached), and calls `collect` with the body of each page.
+// see https://developer.github.com/v3/#pagination
+func (c *Client) paginate(ctx context.Context, url string, collect func(body []byte) error) error {
+	if !strings.Contains(url, "per_page=") {
+		if strings.Contains(url, "?") {
+			url = fmt.Sprintf("%s&per_page=%d", url, perPage)
+		} else {
+			url = fmt.Sprintf("%s?per_page=%d", url, perPage)
+		}
+	}
+	// the owner is taken from the initial URL, since the URLs of the next pages identify the repository by its ID
+	owner := ownerOf(url)
+	for page := 1; url != ""; page++ {
+		if c.maxPages > 0 && page > c.maxPages {
+			log.Warnf("stopped fetching results after %d page(s), next page was '%s'", c.maxPages, url)
+			return nil
+		}
+		body, header, err := c.doAs(ctx, owner, "GET", url, nil)
+		if err != nil {
+			return err
+		}
+		if err := collect(body); err != nil {
+			return errors.Wrapf(err, "unable to decode page %d of '%s'", page, url)
+		}
+		url = nextPageURL(header.Get("Link"))
+	}
+	return nil
+}
+
+// nextPageURL returns the URL of the next page in the given `Link` header, or an empty string if there is no next page
+// eg: `<https://api.github.com/repositories/1/milestones?page=2>; rel="next", <https://api.github.com/repositories/1/milestones?page=5>; rel="last"`
+func nextPageURL(link string) string {
+	for _, l := range strings.Split(link, ",") {
+		segments := strings.Split(strings.TrimSpace(l), ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+func (c *Client) execute(ctx context.Context, method, url string, payload io.Reader, result interface{}) error {
+	body, _, err := c.do(ctx, method, url, payload)
 	if err != nil {
-		return errors.Wrapf(err, "unable to execute HTTP request")
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (c *Client) do(ctx context.Context, method, url string, payload io.Reader) ([]byte, http.Header, error) {
+	return c.doAs(ctx, ownerOf(url), method, url, payload)
+}
+
+// ownerOf returns the owner of the repository in the given Rest API URL
+// (eg: 'fabric8-services' in 'https://api.github.com/repos/fabric8-services/fabric8-auth/milestones/1')
+func ownerOf(url string) string {
+	segments := strings.Split(url, "/")
+	for i, s := range segments {
+		if s == "repos" && i+1 < len(segments) {
+			return segments[i+1]
+		}
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("GITHUB_TOKEN")))
-	resp, err := http.DefaultClient.Do(req)
+	return ""
+}
+
+// doAs executes the request with a token which grants access to the repositories of the given owner
+func (c *Client) doAs(ctx context.Context, owner, method, url string, payload io.Reader) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to execute HTTP request")
+	}
+	token, err := c.tokens.Token(ctx, owner)
 	if err != nil {
-		return errors.Wrapf(err, "unable to execute HTTP request")
+		return nil, nil, errors.Wrapf(err, "unable to obtain a token to execute HTTP request")
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to execute HTTP request")
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return errors.Wrapf(err, "unable to execute HTTP request")
+		return nil, nil, errors.Wrapf(err, "unable to execute HTTP request")
 	}
 	if resp.StatusCode >= 300 {
-		return errors.Errorf("failed to execute query: %d, %s", resp.StatusCode, string(body))
+		return nil, nil, newResponseError(resp, body)
 	}
 	log.Debugf("raw response: %s", string(body))
-	return json.Unmarshal(body, result)
+	return body, resp.Header, nil
 }
@@ -0,0 +1,49 @@
+package github
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/testsupport"
+
+	"github.com/pkg/errors"
+)
+
+func TestFetchMilestone(t *testing.T) {
+	// given
+	gh := testsupport.NewGitHubServer()
+	defer gh.Close()
+	gh.AddRepository("o/a", 1).AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	c := NewClient(Config{BaseURL: gh.URL})
+
+	t.Run("found", func(t *testing.T) {
+		// when
+		m, err := c.FetchMilestone(context.Background(), "o/a", "Sprint 1")
+		// then
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Number != 1 || m.Title != "Sprint 1" || m.State != "open" {
+			t.Fatalf("unexpected milestone: %+v", m)
+		}
+	})
+
+	t.Run("missing milestone", func(t *testing.T) {
+		// when
+		_, err := c.FetchMilestone(context.Background(), "o/a", "Sprint 2")
+		// then the error can be told apart from a missing repository, even when wrapped
+		if _, ok := errors.Cause(errors.Wrap(err, "wrapped")).(MilestoneNotFoundError); !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("missing repository", func(t *testing.T) {
+		// when
+		_, err := c.FetchMilestone(context.Background(), "o/missing", "Sprint 1")
+		// then
+		if _, ok := errors.Cause(err).(NotFoundError); !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
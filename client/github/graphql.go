@@ -0,0 +1,93 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GraphqlRequest a request on the GraphQL API
+type GraphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	// Owner the owner (organization or user) of the queried repositories, used to obtain the
+	// token of the matching installation when authenticating as a GitHub App
+	Owner string `json:"-"`
+}
+
+// the envelope of the responses of the GraphQL API
+// see https://graphql.github.io/graphql-spec/June2018/#sec-Response-Format
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphqlErrors   `json:"errors"`
+}
+
+// GraphqlLocation the location of an error in a GraphQL query
+type GraphqlLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphqlError an error in the response of a GraphQL query
+type GraphqlError struct {
+	Type      string            `json:"type"`
+	Message   string            `json:"message"`
+	Path      []interface{}     `json:"path"`
+	Locations []GraphqlLocation `json:"locations"`
+}
+
+// Error returns the message of the error, along with its type and path
+func (e GraphqlError) Error() string {
+	msg := e.Message
+	if e.Type != "" {
+		msg = fmt.Sprintf("%s: %s", e.Type, msg)
+	}
+	if len(e.Path) > 0 {
+		path := make([]string, len(e.Path))
+		for i, p := range e.Path {
+			path[i] = fmt.Sprintf("%v", p)
+		}
+		msg = fmt.Sprintf("%s (path: '%s')", msg, strings.Join(path, "."))
+	}
+	return msg
+}
+
+// GraphqlErrors the errors in the response of a GraphQL query. The `data` of the response
+// may still have been (partially) decoded when such an error is returned.
+type GraphqlErrors []GraphqlError
+
+// Error returns the messages of all the errors
+func (e GraphqlErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("GraphQL query failed: %s", strings.Join(msgs, "; "))
+}
+
+// ForPath returns the first error whose path starts with the given field, or which is not related to any field
+// (eg: an invalid query), converted into a `NotFoundError`, `ForbiddenError` or `RateLimitError` when applicable.
+// Returns nil if the data for the field can be used.
+func (e GraphqlErrors) ForPath(field string) error {
+	for _, err := range e {
+		if len(err.Path) == 0 || fmt.Sprintf("%v", err.Path[0]) == field {
+			return err.typed()
+		}
+	}
+	return nil
+}
+
+// typed converts the error into a `NotFoundError`, `ForbiddenError` or `RateLimitError` when applicable
+func (e GraphqlError) typed() error {
+	switch e.Type {
+	case "NOT_FOUND":
+		return NotFoundError{Message: e.Error()}
+	case "FORBIDDEN":
+		return ForbiddenError{Message: e.Error()}
+	case "RATE_LIMITED":
+		return RateLimitError{Message: e.Error()}
+	default:
+		return e
+	}
+}
@@ -0,0 +1,261 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimitTransport an `http.RoundTripper` shared by all the requests on the GitHub API, which keeps track
+// of the rate-limit budget, waits until the budget is reset when it is exhausted, and retries the requests
+// which failed because of a secondary rate-limit or a transient server error (with an exponential backoff)
+// see https://developer.github.com/v3/#rate-limiting and https://developer.github.com/v3/#abuse-rate-limits
+type RateLimitTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	backoff    time.Duration
+	lock       sync.Mutex
+	budgets    map[string]*budget
+}
+
+// the rate-limit budget of a given resource ('core', 'graphql', etc.)
+type budget struct {
+	limit     int
+	remaining int
+	reset     time.Time
+	// the highest and lowest remaining values observed during the current window
+	high, low int
+	// the budget consumed during the previous windows
+	consumed int
+	requests int
+}
+
+// NewRateLimitTransport returns a new RateLimitTransport which delegates to the given base transport
+// (or `http.DefaultTransport` if nil), and which retries failed requests up to `maxRetries` times, starting
+// with the given backoff delay which is doubled after each attempt
+func NewRateLimitTransport(base http.RoundTripper, maxRetries int, backoff time.Duration) *RateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RateLimitTransport{
+		base:       base,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		budgets:    map[string]*budget{},
+	}
+}
+
+const (
+	// minimum delay to wait after hitting a secondary rate-limit without a `Retry-After` header
+	secondaryRateLimitDelay = time.Minute
+)
+
+// RoundTrip executes the given request, waiting and retrying if needed
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.waitForBudget(req, resourceOf(req)); err != nil {
+		return nil, err
+	}
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		r, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		resource := t.record(resp)
+		if attempt >= t.maxRetries {
+			return resp, nil
+		}
+		delay, retry, err := t.retryDelay(resp, resource, backoff)
+		if err != nil {
+			return nil, err
+		}
+		if !retry {
+			return resp, nil
+		}
+		resp.Body.Close()
+		log.Warnf("retrying %s %s in %s (attempt %d/%d) after response with status %d", req.Method, req.URL.String(), delay, attempt+1, t.maxRetries, resp.StatusCode)
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+		backoff = backoff * 2
+	}
+}
+
+// rewind returns the request to send for the given attempt, with a fresh body if the request is a retry
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to retry HTTP request")
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+// retryDelay checks if the request which lead to the given response should be retried, and how long to wait before that.
+// The body of the response is restored after it was inspected.
+func (t *RateLimitTransport) retryDelay(resp *http.Response, resource string, backoff time.Duration) (time.Duration, bool, error) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			// secondary rate-limit with an explicit delay
+			return time.Duration(after) * time.Second, true, nil
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			// primary rate-limit: wait until the budget is reset
+			return t.untilReset(resource), true, nil
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "unable to execute HTTP request")
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if msg := strings.ToLower(string(body)); strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse") {
+			if backoff < secondaryRateLimitDelay {
+				backoff = secondaryRateLimitDelay
+			}
+			return backoff, true, nil
+		}
+		return 0, false, nil
+	case resp.StatusCode >= 500:
+		// transient error (eg: '502 Bad Gateway' on GraphQL queries that took too long)
+		return backoff, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
+// waitForBudget waits until the budget of the given resource is reset, if it is currently exhausted
+func (t *RateLimitTransport) waitForBudget(req *http.Request, resource string) error {
+	delay := time.Duration(0)
+	t.lock.Lock()
+	if b, found := t.budgets[resource]; found && b.remaining == 0 {
+		delay = time.Until(b.reset)
+	}
+	t.lock.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	log.Warnf("GitHub rate-limit budget for '%s' is exhausted, waiting %s until it is reset", resource, delay.Round(time.Second))
+	return sleep(req, delay)
+}
+
+func (t *RateLimitTransport) untilReset(resource string) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if b, found := t.budgets[resource]; found {
+		if d := time.Until(b.reset); d > 0 {
+			return d
+		}
+	}
+	return t.backoff
+}
+
+// record updates the rate-limit budget with the headers of the given response, and returns the name of the resource
+func (t *RateLimitTransport) record(resp *http.Response) string {
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = resourceOf(resp.Request)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	b, found := t.budgets[resource]
+	if !found {
+		b = &budget{remaining: -1, high: -1, low: -1}
+		t.budgets[resource] = b
+	}
+	b.requests++
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		// no rate-limit headers in the response
+		return resource
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	reset := b.reset
+	if r, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(r, 0)
+	}
+	if !reset.Equal(b.reset) && b.high >= 0 {
+		// new window: keep track of the budget consumed during the previous one
+		b.consumed += b.high - b.low
+		b.high, b.low = -1, -1
+	}
+	if b.high < 0 {
+		// assume that the request which opened the window consumed 1 point
+		b.high = remaining + 1
+	}
+	if remaining > b.high {
+		b.high = remaining
+	}
+	if b.low < 0 || remaining < b.low {
+		b.low = remaining
+	}
+	b.limit = limit
+	b.remaining = remaining
+	b.reset = reset
+	return resource
+}
+
+// Usage returns a summary of the rate-limit budget consumed so far, per resource
+func (t *RateLimitTransport) Usage() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	resources := make([]string, 0, len(t.budgets))
+	for r := range t.budgets {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	usage := make([]string, 0, len(resources))
+	for _, r := range resources {
+		b := t.budgets[r]
+		consumed := b.consumed
+		if b.high >= 0 {
+			consumed += b.high - b.low
+		}
+		if b.remaining < 0 {
+			// no rate-limit headers in the responses
+			usage = append(usage, fmt.Sprintf("%s: %d request(s)", r, b.requests))
+			continue
+		}
+		usage = append(usage, fmt.Sprintf("%s: %d request(s), %d point(s) consumed, %d/%d remaining until %s",
+			r, b.requests, consumed, b.remaining, b.limit, b.reset.Format(time.RFC3339)))
+	}
+	return strings.Join(usage, "; ")
+}
+
+// resourceOf returns the name of the rate-limit resource for the given request, when it is not in the response headers
+func resourceOf(req *http.Request) string {
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		return "graphql"
+	}
+	return "core"
+}
+
+// sleep waits for the given delay, unless the request is cancelled in the mean time
+func sleep(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,181 @@
+package github
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeServer starts a server which replies to the successive requests with the given handlers
+// (the last handler replies to all remaining requests), and records the bodies of the requests
+func newFakeServer(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *[]string) {
+	lock := sync.Mutex{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		lock.Lock()
+		bodies = append(bodies, string(body))
+		i := len(bodies) - 1
+		lock.Unlock()
+		if i >= len(handlers) {
+			i = len(handlers) - 1
+		}
+		handlers[i](w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+// reply returns a handler which replies with the given status and headers
+func reply(status int, headers ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}
+}
+
+// send sends a request with the given transport, and returns the response (with its body closed)
+func send(t *testing.T, transport http.RoundTripper, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRetryAfterSecondaryRateLimit(t *testing.T) {
+	// given a backoff delay which would exceed the test timeout
+	server, bodies := newFakeServer(t,
+		reply(http.StatusForbidden, "Retry-After", "0"),
+		reply(http.StatusOK))
+	transport := NewRateLimitTransport(nil, 3, time.Hour)
+	// when
+	resp := send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// then the request was retried after the delay of the `Retry-After` header
+	if resp.StatusCode != http.StatusOK || len(*bodies) != 2 {
+		t.Fatalf("unexpected status %d after %d request(s)", resp.StatusCode, len(*bodies))
+	}
+}
+
+func TestRetryAfterPrimaryRateLimitReset(t *testing.T) {
+	// given
+	// (the reset time has a precision of 1 second)
+	reset := time.Now().Add(2 * time.Second)
+	server, bodies := newFakeServer(t,
+		reply(http.StatusForbidden, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10)),
+		reply(http.StatusOK, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "4999", "X-RateLimit-Reset", strconv.FormatInt(reset.Add(time.Hour).Unix(), 10)))
+	transport := NewRateLimitTransport(nil, 3, time.Hour)
+	// when
+	start := time.Now()
+	resp := send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// then the request was retried once the budget was reset
+	if resp.StatusCode != http.StatusOK || len(*bodies) != 2 {
+		t.Fatalf("unexpected status %d after %d request(s)", resp.StatusCode, len(*bodies))
+	}
+	if time.Now().Before(time.Unix(reset.Unix(), 0)) {
+		t.Fatalf("the request was retried before the reset, after %s", time.Since(start))
+	}
+}
+
+func TestWaitForExhaustedBudget(t *testing.T) {
+	// given a budget exhausted by a previous request
+	// (the reset time has a precision of 1 second)
+	reset := time.Now().Add(2 * time.Second)
+	server, bodies := newFakeServer(t,
+		reply(http.StatusOK, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10)),
+		reply(http.StatusOK, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "4999", "X-RateLimit-Reset", strconv.FormatInt(reset.Add(time.Hour).Unix(), 10)))
+	transport := NewRateLimitTransport(nil, 3, time.Hour)
+	send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// when
+	send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// then the second request was sent after the reset
+	if len(*bodies) != 2 || time.Now().Before(time.Unix(reset.Unix(), 0)) {
+		t.Fatalf("the request was sent before the reset")
+	}
+}
+
+func TestRetryServerErrorWithBody(t *testing.T) {
+	// given
+	server, bodies := newFakeServer(t,
+		reply(http.StatusBadGateway),
+		reply(http.StatusOK))
+	transport := NewRateLimitTransport(nil, 3, time.Millisecond)
+	// when
+	resp := send(t, transport, http.MethodPost, server.URL+"/graphql", `{"query":"query { viewer { login } }"}`)
+	// then the body was sent again on the retry
+	if resp.StatusCode != http.StatusOK || len(*bodies) != 2 {
+		t.Fatalf("unexpected status %d after %d request(s)", resp.StatusCode, len(*bodies))
+	}
+	for i, body := range *bodies {
+		if body != `{"query":"query { viewer { login } }"}` {
+			t.Fatalf("unexpected body of request #%d: '%s'", i+1, body)
+		}
+	}
+}
+
+func TestRetryUntilMaxRetries(t *testing.T) {
+	// given
+	server, bodies := newFakeServer(t, reply(http.StatusBadGateway))
+	transport := NewRateLimitTransport(nil, 2, time.Millisecond)
+	// when
+	resp := send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// then the last response is returned after the initial attempt and the 2 retries
+	if resp.StatusCode != http.StatusBadGateway || len(*bodies) != 3 {
+		t.Fatalf("unexpected status %d after %d request(s)", resp.StatusCode, len(*bodies))
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	// given
+	server, bodies := newFakeServer(t, reply(http.StatusForbidden))
+	transport := NewRateLimitTransport(nil, 2, time.Millisecond)
+	// when
+	resp := send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	// then
+	if resp.StatusCode != http.StatusForbidden || len(*bodies) != 1 {
+		t.Fatalf("unexpected status %d after %d request(s)", resp.StatusCode, len(*bodies))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	// given
+	reset := time.Date(2019, 1, 16, 10, 0, 0, 0, time.UTC)
+	next := reset.Add(time.Hour)
+	rateLimit := func(remaining int, reset time.Time) http.HandlerFunc {
+		return reply(http.StatusOK, "X-RateLimit-Resource", "core", "X-RateLimit-Limit", "5000",
+			"X-RateLimit-Remaining", strconv.Itoa(remaining), "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+	server, _ := newFakeServer(t,
+		rateLimit(4999, reset),
+		rateLimit(4997, reset),
+		// new window
+		rateLimit(4999, next),
+		// no rate-limit headers
+		reply(http.StatusOK))
+	transport := NewRateLimitTransport(nil, 0, time.Millisecond)
+	// when
+	for i := 0; i < 3; i++ {
+		send(t, transport, http.MethodGet, server.URL+"/repos/o/a/milestones", "")
+	}
+	send(t, transport, http.MethodPost, server.URL+"/graphql", `{}`)
+	// then 3 points were consumed during the first window, and 1 during the second one
+	expected := "core: 3 request(s), 4 point(s) consumed, 4999/5000 remaining until " + next.Local().Format(time.RFC3339) +
+		"; graphql: 1 request(s)"
+	if usage := transport.Usage(); usage != expected {
+		t.Fatalf("unexpected usage:\n%s\nexpected:\n%s", usage, expected)
+	}
+}
@@ -0,0 +1,200 @@
+package httprecord
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Fixture a recorded request/response pair
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest the recorded request (the headers are not recorded, so no token is stored)
+type FixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// FixtureResponse the recorded response
+type FixtureResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder an `http.RoundTripper` which stores each request/response pair as a fixture in a directory,
+// with the tokens scrubbed
+type Recorder struct {
+	dir  string
+	base http.RoundTripper
+}
+
+// NewRecorder returns a new Recorder which stores the fixtures in the given directory, and which delegates
+// to the given base transport (or `http.DefaultTransport` if nil)
+func NewRecorder(dir string, base http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, os.ModeDir+os.ModePerm); err != nil {
+		return nil, errors.Wrapf(err, "unable to create the fixtures directory")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{
+		dir:  dir,
+		base: base,
+	}, nil
+}
+
+// RoundTrip executes the request and stores the request/response pair
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to record response")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	f := Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    scrub(req.URL.String()),
+			Body:   scrub(string(reqBody)),
+		},
+		Response: FixtureResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       scrub(string(respBody)),
+		},
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to record response")
+	}
+	filename := filepath.Join(r.dir, fixtureName(f.Request))
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return nil, errors.Wrapf(err, "unable to record response")
+	}
+	log.Debugf("recorded %s %s in %s", req.Method, f.Request.URL, filename)
+	return resp, nil
+}
+
+// Replayer an `http.RoundTripper` which serves the responses of the fixtures stored by a Recorder,
+// without accessing the network
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer returns a new Replayer which serves the fixtures in the given directory
+func NewReplayer(dir string) (*Replayer, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the fixtures directory")
+	}
+	return &Replayer{
+		dir: dir,
+	}, nil
+}
+
+// RoundTrip returns the recorded response for the given request, or an error if no response was recorded
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	fr := FixtureRequest{
+		Method: req.Method,
+		URL:    scrub(req.URL.String()),
+		Body:   scrub(string(reqBody)),
+	}
+	filename := filepath.Join(r.dir, fixtureName(fr))
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("no recorded response for %s %s (expected fixture '%s')", fr.Method, fr.URL, filename)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to replay response")
+	}
+	f := Fixture{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "unable to replay response from '%s'", filename)
+	}
+	log.Debugf("replaying %s %s from %s", req.Method, fr.URL, filename)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the body of the given request, and restores it so it can be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read request body")
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// fixtureName returns the name of the fixture file for the given request: a readable prefix
+// followed by a hash of the method, URL and body
+func fixtureName(r FixtureRequest) string {
+	hash := sha256.Sum256([]byte(r.Method + " " + r.URL + "\n" + r.Body))
+	location := strings.SplitN(r.URL, "?", 2)[0]
+	if i := strings.Index(location, "://"); i >= 0 {
+		location = location[i+3:]
+	}
+	prefix := strings.Trim(unsafeChars.ReplaceAllString(location, "_"), "_")
+	if len(prefix) > 80 {
+		prefix = prefix[len(prefix)-80:]
+	}
+	return fmt.Sprintf("%s-%s-%s.json", strings.ToLower(r.Method), prefix, hex.EncodeToString(hash[:])[:12])
+}
+
+var tokenPatterns = []*regexp.Regexp{
+	// eg: installation tokens of GitHub Apps
+	regexp.MustCompile(`("token"\s*:\s*")[^"]*(")`),
+	// eg: tokens passed as query parameters
+	regexp.MustCompile(`((?:access_token|access-token|token)=)[^&"\s]*()`),
+}
+
+// scrub replaces the tokens in the given content
+func scrub(content string) string {
+	for _, p := range tokenPatterns {
+		content = p.ReplaceAllString(content, "${1}REDACTED${2}")
+	}
+	return content
+}
@@ -0,0 +1,142 @@
+package httprecord
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	// given
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// when recording
+	recorded := roundTrip(t, recorder, http.MethodPost, server.URL+"/repos/o/a/milestones", `{"title":"Sprint 1"}`)
+	// then
+	if calls != 1 || recorded != `{"echo":{"title":"Sprint 1"}}` {
+		t.Fatalf("unexpected response: '%s' after %d call(s)", recorded, calls)
+	}
+	// when replaying
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/repos/o/a/milestones", strings.NewReader(`{"title":"Sprint 1"}`))
+	resp, err := replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := ioutil.ReadAll(resp.Body)
+	// then
+	if calls != 1 {
+		t.Fatalf("the server was called while replaying")
+	}
+	if resp.StatusCode != http.StatusCreated || string(replayed) != recorded {
+		t.Fatalf("unexpected replayed response: %d '%s'", resp.StatusCode, replayed)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("Set-Cookie") != "" {
+		t.Fatalf("unexpected replayed headers: %v", resp.Header)
+	}
+}
+
+func TestRecordScrubsTokens(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"token": "v1.installation-token", "expires_at": "2019-01-16T10:00:00Z"}`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// when
+	roundTrip(t, recorder, http.MethodGet, server.URL+"/p1/repositories/1/board?access_token=zenhub-token&page=2", "")
+	// then
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a single fixture, got %v (%v)", files, err)
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := string(data)
+	if strings.Contains(fixture, "zenhub-token") || strings.Contains(fixture, "installation-token") {
+		t.Fatalf("the fixture contains a token:\n%s", fixture)
+	}
+	if !strings.Contains(fixture, "access_token=REDACTED\\u0026page=2") || !strings.Contains(fixture, `\"token\": \"REDACTED\"`) {
+		t.Fatalf("the tokens were not redacted:\n%s", fixture)
+	}
+	// and the request with a different token is replayed from the same fixture
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := roundTrip(t, replayer, http.MethodGet, server.URL+"/p1/repositories/1/board?access_token=other-token&page=2", ""); !strings.Contains(body, "REDACTED") {
+		t.Fatalf("unexpected replayed response: '%s'", body)
+	}
+}
+
+func TestReplayUnmatchedRequest(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/a/milestones?state=all", nil)
+	// when
+	_, err = replayer.RoundTrip(req)
+	// then
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	expected := "no recorded response for GET https://api.github.com/repos/o/a/milestones?state=all (expected fixture '" +
+		filepath.Join(dir, "get-api_github_com_repos_o_a_milestones-") // followed by the hash
+	if !strings.HasPrefix(err.Error(), expected) || !strings.HasSuffix(err.Error(), ".json')") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewReplayerWithMissingDirectory(t *testing.T) {
+	_, err := NewReplayer(filepath.Join(t.TempDir(), "missing"))
+	if err == nil || !strings.HasPrefix(err.Error(), "unable to read the fixtures directory") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// roundTrip sends a request with the given transport, and returns the body of the response
+func roundTrip(t *testing.T, transport http.RoundTripper, method, url, body string) string {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,62 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NotFoundError the error returned when a resource (eg: a repository or an issue) could not be found on ZenHub
+type NotFoundError struct {
+	Message string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("not found: %s", e.Message)
+}
+
+// UnauthorizedError the error returned when the request could not be authenticated (eg: invalid token)
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized: %s", e.Message)
+}
+
+// RateLimitError the error returned when the rate-limit of the token was reached
+type RateLimitError struct {
+	Message string
+	// Reset the time at which the budget will be reset (zero if unknown)
+	Reset time.Time
+}
+
+func (e RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("rate limit exceeded: %s", e.Message)
+	}
+	return fmt.Sprintf("rate limit exceeded until %s: %s", e.Reset.Format(time.RFC3339), e.Message)
+}
+
+// newResponseError converts the given error response into a typed error, when applicable
+// see https://github.com/ZenHubIO/API#errors
+func newResponseError(resp *http.Response, body []byte) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return UnauthorizedError{Message: string(body)}
+	case http.StatusForbidden:
+		// ZenHub responds with a '403 Forbidden' when the rate-limit was reached
+		reset := time.Time{}
+		if r, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			reset = time.Unix(r, 0)
+		}
+		return RateLimitError{Message: string(body), Reset: reset}
+	case http.StatusNotFound:
+		return NotFoundError{Message: string(body)}
+	default:
+		return errors.Errorf("failed to execute query: %d, %s", resp.StatusCode, string(body))
+	}
+}
@@ -0,0 +1,130 @@
+package zenhub
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimitTransport an `http.RoundTripper` shared by all the requests on the ZenHub API, which paces the requests
+// across all goroutines to stay within the rate-limit of the token, and which waits until the rate-limit is reset
+// and retries when a request was rejected with a '403 Forbidden' response because the limit was reached.
+// see https://github.com/ZenHubIO/API#api-rate-limit
+type RateLimitTransport struct {
+	base       http.RoundTripper
+	interval   time.Duration
+	maxRetries int
+	lock       sync.Mutex
+	// the earliest time at which the next request can be sent
+	next time.Time
+	// the state of the rate-limit, as reported in the most recent response
+	limit int
+	used  int
+	reset time.Time
+}
+
+// NewRateLimitTransport returns a new RateLimitTransport which delegates to the given base transport
+// (or `http.DefaultTransport` if nil), which sends at most `requestsPerMinute` requests per minute,
+// and which retries rate-limited requests up to `maxRetries` times
+func NewRateLimitTransport(base http.RoundTripper, requestsPerMinute, maxRetries int) *RateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	interval := time.Duration(0)
+	if requestsPerMinute > 0 {
+		interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return &RateLimitTransport{
+		base:       base,
+		interval:   interval,
+		maxRetries: maxRetries,
+	}
+}
+
+// RoundTrip executes the given request when the pace allows it, waiting and retrying if the rate-limit was reached
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := sleep(req, t.reserve()); err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		reset, limited := t.record(resp)
+		if !limited || attempt >= t.maxRetries {
+			return resp, nil
+		}
+		resp.Body.Close()
+		delay := time.Until(reset)
+		log.Warnf("ZenHub rate-limit reached, waiting %s until it is reset (attempt %d/%d)", delay.Round(time.Second), attempt+1, t.maxRetries)
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reserve reserves the next slot to send a request, and returns the delay to wait until then
+func (t *RateLimitTransport) reserve() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := time.Now()
+	start := now
+	if t.next.After(start) {
+		start = t.next
+	}
+	if t.limit > 0 && t.used >= t.limit && t.reset.After(start) {
+		// budget exhausted: wait until the rate-limit is reset
+		start = t.reset
+		t.used = 0
+	}
+	t.next = start.Add(t.interval)
+	if t.limit > 0 {
+		t.used++
+	}
+	return start.Sub(now)
+}
+
+// record updates the state of the rate-limit with the headers of the given response,
+// and returns the reset time if the request was rejected because the rate-limit was reached
+func (t *RateLimitTransport) record(resp *http.Response) (time.Time, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		t.limit = limit
+	}
+	if used, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Used")); err == nil {
+		t.used = used
+	}
+	reset := time.Time{}
+	if r, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(r, 0)
+		t.reset = reset
+	}
+	if resp.StatusCode != http.StatusForbidden || reset.IsZero() {
+		return time.Time{}, false
+	}
+	// no more request until the rate-limit is reset
+	if t.next.Before(reset) {
+		t.next = reset
+	}
+	return reset, true
+}
+
+// sleep waits for the given delay, unless the request is cancelled in the mean time
+func sleep(req *http.Request, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,163 @@
+package zenhub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeServer starts a server which replies to the successive requests with the given handlers
+// (the last handler replies to all remaining requests), and records the times at which the requests were received
+func newFakeServer(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, func() []time.Time) {
+	lock := sync.Mutex{}
+	times := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		times = append(times, time.Now())
+		i := len(times) - 1
+		lock.Unlock()
+		if i >= len(handlers) {
+			i = len(handlers) - 1
+		}
+		handlers[i](w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]time.Time{}, times...)
+	}
+}
+
+// reply returns a handler which replies with the given status and headers
+func reply(status int, headers ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}
+}
+
+// send sends a GET request with the given transport, and returns the status of the response
+func send(t *testing.T, transport http.RoundTripper, url string) int {
+	t.Helper()
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPaceRequestsAcrossGoroutines(t *testing.T) {
+	// given a rate-limit of 1 request every 100ms
+	server, times := newFakeServer(t, reply(http.StatusOK))
+	transport := NewRateLimitTransport(nil, 600, 3)
+	// when
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+		}()
+	}
+	wg.Wait()
+	// then the requests were spaced by the interval
+	received := times()
+	if len(received) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(received))
+	}
+	for i := 1; i < len(received); i++ {
+		// (with some tolerance for the scheduling of the goroutines and the server)
+		if interval := received[i].Sub(received[i-1]); interval < 90*time.Millisecond {
+			t.Errorf("request #%d was received %s after the previous one", i+1, interval)
+		}
+	}
+}
+
+func TestNoPacingWithoutRateLimit(t *testing.T) {
+	// given
+	server, times := newFakeServer(t, reply(http.StatusOK))
+	transport := NewRateLimitTransport(nil, 0, 3)
+	// when
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+	}
+	// then
+	if len(times()) != 4 || time.Since(start) > time.Second {
+		t.Fatalf("unexpected %d request(s) in %s", len(times()), time.Since(start))
+	}
+}
+
+func TestRetryAfterRateLimitReset(t *testing.T) {
+	// given
+	// (the reset time has a precision of 1 second)
+	reset := time.Now().Add(2 * time.Second)
+	server, times := newFakeServer(t,
+		reply(http.StatusForbidden, "X-RateLimit-Limit", "100", "X-RateLimit-Used", "100", "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10)),
+		reply(http.StatusOK, "X-RateLimit-Limit", "100", "X-RateLimit-Used", "1", "X-RateLimit-Reset", strconv.FormatInt(reset.Add(time.Minute).Unix(), 10)))
+	transport := NewRateLimitTransport(nil, 0, 3)
+	// when
+	status := send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+	// then the request was retried once the rate-limit was reset
+	received := times()
+	if status != http.StatusOK || len(received) != 2 {
+		t.Fatalf("unexpected status %d after %d request(s)", status, len(received))
+	}
+	if received[1].Before(time.Unix(reset.Unix(), 0)) {
+		t.Fatalf("the request was retried before the reset")
+	}
+}
+
+func TestWaitForExhaustedRateLimit(t *testing.T) {
+	// given a rate-limit exhausted by a previous request
+	// (the reset time has a precision of 1 second)
+	reset := time.Now().Add(2 * time.Second)
+	server, times := newFakeServer(t,
+		reply(http.StatusOK, "X-RateLimit-Limit", "100", "X-RateLimit-Used", "100", "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10)),
+		reply(http.StatusOK, "X-RateLimit-Limit", "100", "X-RateLimit-Used", "1", "X-RateLimit-Reset", strconv.FormatInt(reset.Add(time.Minute).Unix(), 10)))
+	transport := NewRateLimitTransport(nil, 0, 3)
+	send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+	// when
+	send(t, transport, server.URL+"/p1/repositories/1/issues/2")
+	// then the second request was sent after the reset
+	received := times()
+	if len(received) != 2 || received[1].Before(time.Unix(reset.Unix(), 0)) {
+		t.Fatalf("the request was sent before the reset")
+	}
+}
+
+func TestRetryRateLimitedRequestUntilMaxRetries(t *testing.T) {
+	// given a rate-limit which is always reached
+	server, times := newFakeServer(t, func(w http.ResponseWriter, req *http.Request) {
+		reply(http.StatusForbidden, "X-RateLimit-Limit", "100", "X-RateLimit-Used", "100",
+			"X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))(w, req)
+	})
+	transport := NewRateLimitTransport(nil, 0, 2)
+	// when
+	status := send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+	// then the last response is returned after the initial attempt and the 2 retries
+	if status != http.StatusForbidden || len(times()) != 3 {
+		t.Fatalf("unexpected status %d after %d request(s)", status, len(times()))
+	}
+}
+
+func TestNoRetryOnForbiddenWithoutRateLimit(t *testing.T) {
+	// given a response which is not caused by the rate-limit (eg: invalid token)
+	server, times := newFakeServer(t, reply(http.StatusForbidden))
+	transport := NewRateLimitTransport(nil, 0, 3)
+	// when
+	status := send(t, transport, server.URL+"/p1/repositories/1/issues/1")
+	// then
+	if status != http.StatusForbidden || len(times()) != 1 {
+		t.Fatalf("unexpected status %d after %d request(s)", status, len(times()))
+	}
+}
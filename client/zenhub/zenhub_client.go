@@ -1,26 +1,156 @@
 package zenhub
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
-// QueryIssueEvents retrieves the events for the issue
-func QueryIssueEvents(repoID, number int64, result interface{}) error {
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf("https://api.zenhub.io/p1/repositories/%d/issues/%d/events", repoID, number),
-		nil)
+// DefaultBaseURL the default base URL of the ZenHub API
+const DefaultBaseURL = "https://api.zenhub.io"
+
+// Config the configuration of the ZenHub client
+type Config struct {
+	// BaseURL the base URL of the API (eg: 'https://zenhub.example.com/api' for ZenHub Enterprise)
+	BaseURL string
+	// HTTPClient the underlying HTTP client. Uses `http.DefaultClient` if nil
+	HTTPClient *http.Client
+	// Token the token to authenticate the requests
+	Token string
+}
+
+// Client the client for the ZenHub API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// NewClient returns a new client configured with the given config
+func NewClient(config Config) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
+		httpClient: config.HTTPClient,
+		token:      config.Token,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	return c
+}
+
+// Estimate the estimate of an issue, in story points
+type Estimate struct {
+	Value float64 `json:"value"`
+}
+
+// Issue the ZenHub data of an issue
+type Issue struct {
+	Estimate *Estimate `json:"estimate,omitempty"`
+	Pipeline struct {
+		Name string `json:"name"`
+	} `json:"pipeline"`
+	IsEpic bool `json:"is_epic"`
+}
+
+// GetIssue retrieves the ZenHub data of the given issue (estimate, pipeline, etc.)
+// see https://github.com/ZenHubIO/API#get-issue-data
+func (c *Client) GetIssue(ctx context.Context, repoID, number int64) (Issue, error) {
+	result := Issue{}
+	err := c.get(ctx, fmt.Sprintf("%s/p1/repositories/%d/issues/%d", c.baseURL, repoID, number), &result)
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to get data of issue %d", number)
+	}
+	return result, nil
+}
+
+// EpicIssue an issue which is an epic, or which belongs to an epic
+type EpicIssue struct {
+	IssueNumber int64     `json:"issue_number"`
+	RepoID      int64     `json:"repo_id"`
+	IssueURL    string    `json:"issue_url,omitempty"`
+	Estimate    *Estimate `json:"estimate,omitempty"`
+	IsEpic      bool      `json:"is_epic"`
+}
+
+// ListEpics lists the epics of the given repository
+// see https://github.com/ZenHubIO/API#get-epics-for-a-repository
+func (c *Client) ListEpics(ctx context.Context, repoID int64) ([]EpicIssue, error) {
+	result := struct {
+		EpicIssues []EpicIssue `json:"epic_issues"`
+	}{}
+	err := c.get(ctx, fmt.Sprintf("%s/p1/repositories/%d/epics", c.baseURL, repoID), &result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list epics of repository %d", repoID)
+	}
+	return result.EpicIssues, nil
+}
+
+// Epic the ZenHub data of an epic, along with the issues it contains
+type Epic struct {
+	TotalEpicEstimates *Estimate   `json:"total_epic_estimates,omitempty"`
+	Estimate           *Estimate   `json:"estimate,omitempty"`
+	Issues             []EpicIssue `json:"issues"`
+}
+
+// GetEpic retrieves the ZenHub data of the given epic
+// see https://github.com/ZenHubIO/API#get-epic-data
+func (c *Client) GetEpic(ctx context.Context, repoID, number int64) (Epic, error) {
+	result := Epic{}
+	err := c.get(ctx, fmt.Sprintf("%s/p1/repositories/%d/epics/%d", c.baseURL, repoID, number), &result)
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to get data of epic %d", number)
+	}
+	return result, nil
+}
+
+// Board the ZenHub board of a repository in a workspace
+type Board struct {
+	Pipelines []Pipeline `json:"pipelines"`
+}
+
+// Pipeline a pipeline on a ZenHub board, along with the issues it contains
+type Pipeline struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Issues []BoardIssue `json:"issues"`
+}
+
+// BoardIssue an issue in a pipeline on a ZenHub board
+type BoardIssue struct {
+	IssueNumber int64     `json:"issue_number"`
+	Estimate    *Estimate `json:"estimate,omitempty"`
+	Position    int       `json:"position"`
+	IsEpic      bool      `json:"is_epic"`
+}
+
+// GetBoard retrieves the board of the given repository in the given workspace, i.e., the current pipeline of every issue in a single call
+// see https://github.com/ZenHubIO/API#get-a-zenhub-board-for-a-repository
+func (c *Client) GetBoard(ctx context.Context, workspaceID string, repoID int64) (Board, error) {
+	result := Board{}
+	err := c.get(ctx, fmt.Sprintf("%s/p1/workspaces/%s/repositories/%d/board", c.baseURL, workspaceID, repoID), &result)
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to get board of repository %d in workspace '%s'", repoID, workspaceID)
+	}
+	return result, nil
+}
+
+func (c *Client) get(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return errors.Wrapf(err, "unable to get data on ZenHub")
 	}
-	req.Header.Add("X-Authentication-Token", os.Getenv("ZENHUB_TOKEN"))
-	resp, err := http.DefaultClient.Do(req)
+	req.Header.Add("X-Authentication-Token", c.token)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return errors.Wrapf(err, "unable to get data on ZenHub")
 	}
@@ -30,8 +160,8 @@ func QueryIssueEvents(repoID, number int64, result interface{}) error {
 		return errors.Wrapf(err, "unable to get data on ZenHub")
 	}
 	if resp.StatusCode != 200 {
-		return errors.Errorf("failed to execute query: %s", string(body))
+		return newResponseError(resp, body)
 	}
-	log.Debugf("raw response for issue %d: %s", number, string(body))
+	log.Debugf("raw response for '%s': %s", url, string(body))
 	return json.Unmarshal(body, result)
 }
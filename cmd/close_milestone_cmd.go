@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"sync"
+	"context"
 
 	"github.com/fabric8-services/fabric8-changelog/client/github"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -14,7 +15,7 @@ func NewCloseMilestoneCmd() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "close-milestone",
 		Short: "Close the milestone",
-		RunE:  closeMilestone,
+		RunE:  terminateOnExit(closeMilestone),
 		Args:  cobra.ExactArgs(0),
 	}
 	c.Flags().StringVarP(&name, "name", "", "", "the milestone to close (ef: 'Sprint 123')")
@@ -22,31 +23,30 @@ func NewCloseMilestoneCmd() *cobra.Command {
 }
 
 func closeMilestone(cmd *cobra.Command, args []string) error {
-	wg := sync.WaitGroup{}
-	for _, repo := range repos {
-		wg.Add(1)
-		go func(repo string) {
-			defer wg.Done()
-			// first, we need to retrieve the milestone numbers, given their name
-			m, err := github.FetchMilestone(repo, name)
-			if err != nil {
-				log.WithError(err).Errorf("unable to close milestone '%s' in repository '%s'", name, repo)
-				return
-			}
-			if m.State != "open" {
-				log.Errorf("milestone '%s' in repository '%s' is already closed.", name, repo)
-				return
-			}
-			// finally, close the old milestone
-			err = github.CloseMilestone(&m)
-			if err != nil {
-				log.WithError(err).Errorf("unable to close milestone '%s'", m.URL)
-				return
-			}
-			log.Infof("closed milestone %s", m.URL)
-		}(repo)
-	}
-	wg.Wait()
+	results := forEachRepo(commandCtx, repos, func(ctx context.Context, repo string) (interface{}, error) {
+		// first, we need to retrieve the milestone numbers, given their name
+		m, err := githubClient.FetchMilestone(ctx, repo, name)
+		// the milestone does not exist (as opposed to a 'NotFoundError' when the repository itself does not exist)
+		if _, ok := errors.Cause(err).(github.MilestoneNotFoundError); ok {
+			return nil, skipRepo("no milestone '%s'", name)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to close milestone '%s'", name)
+		}
+		if m.State != "open" {
+			return nil, skipRepo("milestone '%s' is already closed", name)
+		}
+		// finally, close the old milestone
+		err = githubClient.CloseMilestone(ctx, &m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to close milestone '%s'", m.URL)
+		}
+		log.Infof("closed milestone %s", m.URL)
+		return m, nil
+	})
 	log.Debug("done")
-	return nil
+	if err := summarize(cmd, results); err != nil {
+		return err
+	}
+	return checkNotAllSkipped(cmd, results)
 }
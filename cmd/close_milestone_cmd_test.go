@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloseMilestone(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	sprint1 := a.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	sprint2 := a.AddMilestone("Sprint 2", time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC))
+	// when
+	_, err := runCommand(t, gh, zh, "close-milestone", "-r", "o/a", "--name", "Sprint 1")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprint1.State != "closed" || sprint2.State != "open" {
+		t.Fatalf("unexpected states: '%s' and '%s'", sprint1.State, sprint2.State)
+	}
+}
+
+func TestCloseMilestoneInMissingRepository(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	sprint1 := a.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	// when
+	_, err := runCommand(t, gh, zh, "close-milestone", "-r", "o/a,o/missing", "--name", "Sprint 1")
+	// then the command fails, but the milestone was closed in the other repository
+	if err == nil || err.Error() != "close-milestone failed on 1 of 2 repositories" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sprint1.State != "closed" {
+		t.Fatalf("the milestone was not closed in 'o/a'")
+	}
+}
+
+func TestCloseMilestoneWithMistypedName(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	sprint1 := a.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	gh.AddRepository("o/b", 2).AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	// when
+	_, err := runCommand(t, gh, zh, "close-milestone", "-r", "o/a,o/b", "--name", "Spritn 1")
+	// then all repositories are skipped, and the command fails
+	if err == nil || err.Error() != "close-milestone was skipped on all 2 repositories" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sprint1.State != "open" {
+		t.Fatalf("the milestone was closed in 'o/a'")
+	}
+}
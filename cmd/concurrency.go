@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// forEach calls the given function for each index in `[0, n)`, with at most `concurrency` calls running at the same time,
+// and waits until all calls returned
+func forEach(n int, f func(idx int)) {
+	workers := concurrency
+	if workers <= 0 || workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				f(idx)
+			}
+		}()
+	}
+	for idx := 0; idx < n; idx++ {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// repoResult the outcome of the processing of a single repository
+type repoResult struct {
+	repo  string
+	value interface{}
+	err   error
+}
+
+// repoFunc the function which processes a single repository, and returns a value to aggregate
+type repoFunc func(ctx context.Context, repo string) (interface{}, error)
+
+// forEachRepo calls the given function for each repository, with at most `concurrency` repositories being processed
+// at the same time, and returns the results in the same order as the repositories. The results are gathered
+// in the calling goroutine, so the caller can aggregate them without any lock. Once the context is cancelled,
+// the remaining repositories are not processed and their result contains the error of the context.
+func forEachRepo(ctx context.Context, repos []string, f repoFunc) []repoResult {
+	type outcome struct {
+		idx    int
+		result repoResult
+	}
+	outcomes := make(chan outcome, len(repos))
+	forEach(len(repos), func(idx int) {
+		repo := repos[idx]
+		if err := ctx.Err(); err != nil {
+			outcomes <- outcome{idx: idx, result: repoResult{repo: repo, err: err}}
+			return
+		}
+		value, err := f(ctx, repo)
+		outcomes <- outcome{idx: idx, result: repoResult{repo: repo, value: value, err: err}}
+	})
+	close(outcomes)
+	results := make([]repoResult, len(repos))
+	for o := range outcomes {
+		results[o.idx] = o.result
+	}
+	return results
+}
+
+// checkInterrupted returns an error if the given context was cancelled (eg: Ctrl-C) or if it timed out
+func checkInterrupted(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.Errorf("command timed out after %s", timeout)
+	case context.Canceled:
+		return errors.New("command was interrupted")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config the content of the configuration file
+// eg:
+//
+//	default-group: auth-team
+//	groups:
+//	  auth-team:
+//	    repositories:
+//	    - fabric8-services/fabric8-auth
+//	    - fabric8-services/fabric8-auth-client
+//	    pipelines:
+//	    - In Progress
+//	    - Review/QA
+//	    format: asciidoc
+//	    milestone-name: 'Sprint {{ .End.Format "2006-01-02" }}'
+type Config struct {
+	// DefaultGroup the group of repositories on which the commands apply when none of `--group`, `--repositories` or `--org` is specified
+	DefaultGroup string `yaml:"default-group"`
+	// Groups the groups of repositories, by name
+	Groups map[string]RepoGroup `yaml:"groups"`
+}
+
+// RepoGroup a named group of repositories, along with the default values of the flags of the commands which apply on them
+type RepoGroup struct {
+	Repositories []string `yaml:"repositories"`
+	// Pipelines the default ZenHub pipelines of the issues to include in the report
+	Pipelines []string `yaml:"pipelines"`
+	// Format the default output format of the report
+	Format string `yaml:"format"`
+	// MilestoneName the template of the name of the milestones created when no `--name` is specified,
+	// with the end date of the milestone as `.End`
+	MilestoneName string `yaml:"milestone-name"`
+}
+
+// the default location of the configuration file, relative to the home directory
+const defaultConfigFile = ".config/fabric8-changelog.yaml"
+
+var configFile, groupName string
+
+// the group of repositories on which the command applies, if any
+var group *RepoGroup
+
+// loadConfig loads the configuration file at the given location, or at the default location if empty.
+// A missing file is only an error if its location was explicitly given.
+func loadConfig(filename string) (Config, error) {
+	config := Config{}
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.WithError(err).Debug("unable to locate the default configuration file")
+			return config, nil
+		}
+		filename = filepath.Join(home, defaultConfigFile)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return config, nil
+		}
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, errors.Wrapf(err, "unable to read the configuration file")
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, errors.Wrapf(err, "unable to parse the configuration file '%s'", filename)
+	}
+	log.Debugf("loaded configuration from '%s'", filename)
+	return config, nil
+}
+
+// initGroup selects the group of repositories on which the command applies (if any), and applies its defaults
+// to the flags which were not explicitly specified
+func initGroup(cmd *cobra.Command) error {
+	group = nil
+	config, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	selected := groupName
+	if selected != "" && cmd.Flags().Changed("repositories") {
+		return errors.New("the '--group' and '--repositories' flags cannot be used together")
+	}
+	if selected == "" && !cmd.Flags().Changed("repositories") && len(orgs) == 0 {
+		selected = config.DefaultGroup
+	}
+	if selected == "" {
+		return nil
+	}
+	g, found := config.Groups[selected]
+	if !found {
+		return errors.Errorf("unknown group of repositories '%s' (available groups: %v)", selected, groupNames(config))
+	}
+	if len(g.Repositories) == 0 {
+		return errors.Errorf("no repository in group '%s'", selected)
+	}
+	log.Debugf("using group of repositories '%s': %v", selected, g.Repositories)
+	group = &g
+	repos = g.Repositories
+	if len(g.Pipelines) > 0 && !cmd.Flags().Changed("pipelines") {
+		pipelines = g.Pipelines
+	}
+	if g.Format != "" && !cmd.Flags().Changed("format") {
+		outputFormat = g.Format
+	}
+	return nil
+}
+
+// groupNames returns the sorted names of the groups in the given configuration
+func groupNames(config Config) []string {
+	names := make([]string, 0, len(config.Groups))
+	for n := range config.Groups {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// milestoneName returns the name of the milestone with the given end date, using the template of the current group
+func milestoneName(end time.Time) (string, error) {
+	if group == nil || group.MilestoneName == "" {
+		return "", errors.New("missing milestone name (use the '--name' flag, or a group with a 'milestone-name' template)")
+	}
+	tmpl, err := template.New("milestone-name").Parse(group.MilestoneName)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid milestone name template")
+	}
+	result := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(result, struct{ End time.Time }{End: end}); err != nil {
+		return "", errors.Wrapf(err, "invalid milestone name template")
+	}
+	return result.String(), nil
+}
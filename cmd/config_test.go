@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+)
+
+const groupsConfig = "testdata/groups-config.yaml"
+
+func TestSelectRepositoriesWithGroup(t *testing.T) {
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "group",
+			args:     []string{"-g", "team-b"},
+			expected: "o/c\n",
+		},
+		{
+			name:     "default group",
+			args:     []string{},
+			expected: "o/a\no/b\n",
+		},
+		{
+			name:     "repositories instead of the default group",
+			args:     []string{"-r", "o/x"},
+			expected: "o/x\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			// when
+			out, err := runCommand(t, gh, zh, append([]string{"repos", "list", "--config", groupsConfig}, tc.args...)...)
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tc.expected {
+				t.Fatalf("unexpected repositories:\n%s\nexpected:\n%s", out, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSelectInvalidGroup(t *testing.T) {
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown group",
+			args:     []string{"-g", "team-c"},
+			expected: "unknown group of repositories 'team-c' (available groups: [team-a team-b])",
+		},
+		{
+			name:     "group and repositories",
+			args:     []string{"-g", "team-b", "-r", "o/x"},
+			expected: "the '--group' and '--repositories' flags cannot be used together",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			// when
+			_, err := runCommand(t, gh, zh, append([]string{"repos", "list", "--config", groupsConfig}, tc.args...)...)
+			// then
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateReportWithGroupDefaults(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-g", "team-a", "--since", "2019-01-09", "--output", "-", "--config", groupsConfig)
+	// then the report is generated in the format and with the pipelines of the group
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "**Review/QA (2 points)**") || strings.Contains(out, "In Progress") {
+		t.Fatalf("expected a markdown report with the 'Review/QA' pipeline only:\n%s", out)
+	}
+}
+
+func TestGenerateReportWithFlagsOverridingGroupDefaults(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-g", "team-a", "--since", "2019-01-09", "--output", "-", "--config", groupsConfig,
+		"--format", "json", "--pipelines", InProgress)
+	// then the flags take precedence over the defaults of the group
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v\n%s", err, out)
+	}
+	if len(r.InProgressIssues) != 1 || r.InProgressIssues[0].Name != InProgress || r.TotalPoints != 3 {
+		t.Fatalf("unexpected pipelines: %+v", r.InProgressIssues)
+	}
+	if len(r.MergedPRs) != 2 {
+		t.Fatalf("unexpected merged pull requests: %+v", r.MergedPRs)
+	}
+}
+
+func TestCreateMilestoneWithNameTemplate(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	b := gh.AddRepository("o/b", 2)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "--end", "2019-01-29", "--config", groupsConfig)
+	// then the milestones are named after the template of the default group
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []string{"o/a", "o/b"} {
+		if m := gh.Repository(r).MilestoneByTitle("Sprint 2019-01-29"); m == nil || !m.DueOn.Equal(time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected milestone in '%s': %+v", r, m)
+		}
+	}
+	if len(a.Milestones) != 1 || len(b.Milestones) != 1 {
+		t.Fatalf("unexpected milestones: %d and %d", len(a.Milestones), len(b.Milestones))
+	}
+}
+
+func TestCreateMilestoneWithNameOverridingTemplate(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	gh.AddRepository("o/b", 2)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "--end", "2019-01-29", "--name", "Sprint 2", "--config", groupsConfig)
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.MilestoneByTitle("Sprint 2") == nil || len(a.Milestones) != 1 {
+		t.Fatalf("unexpected milestones: %d", len(a.Milestones))
+	}
+}
+
+func TestCreateMilestoneWithoutNameTemplate(t *testing.T) {
+	// given a group without a 'milestone-name' template
+	gh, zh := newTestServers(t)
+	gh.AddRepository("o/c", 3)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "-g", "team-b", "--end", "2019-01-29", "--config", groupsConfig)
+	// then
+	if err == nil || !strings.HasPrefix(err.Error(), "missing milestone name") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package cmd
 
 import (
+	"context"
 	"sort"
-	"sync"
 	"time"
 
 	"github.com/fabric8-services/fabric8-changelog/client/github"
@@ -20,10 +20,10 @@ func NewCreateMilestoneCmd() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "new-milestone",
 		Short: "Creates a new milestone",
-		RunE:  generateMilestone,
+		RunE:  terminateOnExit(generateMilestone),
 		Args:  cobra.ExactArgs(0),
 	}
-	c.Flags().StringVarP(&name, "name", "n", "", "the name of the milestone to create (ef: 'Sprint 123')")
+	c.Flags().StringVarP(&name, "name", "n", "", "the name of the milestone to create (ef: 'Sprint 123' - default: the 'milestone-name' template of the group)")
 	c.Flags().StringVarP(&endDate, "end", "e", "", "the end date for the sprint (format: '2006-01-02')")
 	return c
 }
@@ -34,23 +34,29 @@ func generateMilestone(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return errors.Wrap(err, "invalid value for the 'end' date")
 	}
+	if name == "" {
+		if name, err = milestoneName(end); err != nil {
+			return err
+		}
+	}
 	log.Debugf("creating milestone '%s' with end date '%s' on %v...", name, end.String(), repos)
 
-	wg := sync.WaitGroup{}
-	for i, repo := range repos {
-		wg.Add(1)
-		// process in a go routine to parallelize the I/O tasks
-		go func(idx int, repo string) {
-			defer wg.Done()
-			log.Debugf("creating milestone '%s' for repo '%s'...", name, repo)
-			m, err := github.CreateMilestone(repo, name, end)
-			if err != nil {
-				log.WithError(err).Errorf("failed to create milestone '%s' for repo '%s'", name, repo)
-				return
+	results := forEachRepo(commandCtx, repos, func(ctx context.Context, repo string) (interface{}, error) {
+		log.Debugf("creating milestone '%s' for repo '%s'...", name, repo)
+		m, err := githubClient.CreateMilestone(ctx, repo, name, end)
+		if verr, ok := errors.Cause(err).(github.ValidationError); ok && verr.HasCode("already_exists") {
+			// the milestone was already created (eg: during a previous run of the command)
+			m, err = githubClient.FetchMilestone(ctx, repo, name)
+			if err == nil {
+				log.Infof("milestone already exists for repo: '%s'", m.URL)
+				return m, skipRepo("milestone '%s' already exists", name)
 			}
-			log.Infof("created milestone for repo: '%s'", m.URL)
-		}(i, repo)
-	}
-	wg.Wait()
-	return nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create milestone '%s'", name)
+		}
+		log.Infof("created milestone for repo: '%s'", m.URL)
+		return m, nil
+	})
+	return summarize(cmd, results)
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateMilestone(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	b := gh.AddRepository("o/b", 2)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "-r", "o/a,o/b", "--name", "Sprint 2", "--end", "2019-01-29")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []string{"o/a", "o/b"} {
+		m := gh.Repository(r).MilestoneByTitle("Sprint 2")
+		if m == nil || m.State != "open" || !m.DueOn.Equal(time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected milestone in '%s': %+v", r, m)
+		}
+	}
+	if len(a.Milestones) != 1 || len(b.Milestones) != 1 {
+		t.Fatalf("unexpected milestones: %d and %d", len(a.Milestones), len(b.Milestones))
+	}
+}
+
+func TestCreateMilestoneWhichAlreadyExists(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	a.AddMilestone("Sprint 2", time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC))
+	b := gh.AddRepository("o/b", 2)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "-r", "o/a,o/b", "--name", "Sprint 2", "--end", "2019-01-29")
+	// then the repository in which the milestone already exists is skipped
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Milestones) != 1 || len(b.Milestones) != 1 || b.MilestoneByTitle("Sprint 2") == nil {
+		t.Fatalf("unexpected milestones: %d and %d", len(a.Milestones), len(b.Milestones))
+	}
+}
+
+func TestCreateMilestoneWithQuotesInName(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	// when
+	_, err := runCommand(t, gh, zh, "new-milestone", "-r", "o/a", "--name", `Sprint "2" \ C:\dir\`, "--end", "2019-01-29")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.MilestoneByTitle(`Sprint "2" \ C:\dir\`) == nil {
+		t.Fatalf("milestone not found among %d milestone(s)", len(a.Milestones))
+	}
+}
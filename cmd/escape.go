@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"strings"
+	"text/template"
+)
+
+// escapeFunc the function which escapes a text (eg: the title of a pull request) so that it is rendered as-is in a given format
+type escapeFunc func(text string) string
+
+// escapeFuncs the escaping functions, per extension of the template files
+var escapeFuncs = map[string]escapeFunc{
+	"adoc":     escapeAsciidoc,
+	"asciidoc": escapeAsciidoc,
+	"md":       escapeMarkdown,
+	"markdown": escapeMarkdown,
+	"htm":      escapeHTML,
+	"html":     escapeHTML,
+	"slack":    escapeSlack,
+}
+
+// escapeFuncFor returns the escaping function for the templates with the given extension,
+// which leaves the text unchanged if the extension is unknown (eg: 'txt')
+func escapeFuncFor(extension string) escapeFunc {
+	if f, found := escapeFuncs[extension]; found {
+		return f
+	}
+	return func(text string) string {
+		return text
+	}
+}
+
+// escapeFuncMap returns the function map in which the `escape` function of the templates is the given function
+func escapeFuncMap(escape escapeFunc) template.FuncMap {
+	return template.FuncMap{
+		"escape": escape,
+	}
+}
+
+// the characters which have a meaning in the inline content of asciidoc
+// (formatting marks, macros, attribute references and replacements)
+const asciidocSpecialChars = "*_`#^~+[]{}\\<>&'\"|"
+
+// the line breaks, which would end the current paragraph or list item in asciidoc
+var lineBreakReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// escapeAsciidoc escapes the given text so that it is rendered as-is in asciidoc: a text which contains special
+// characters is wrapped in inline passthroughs (`+...+`) in which only the special characters of HTML are substituted.
+// The `+` characters, which cannot appear in these passthroughs, are passed through on their own (`pass:[+]`), and the
+// line breaks are replaced with spaces.
+// eg: 'Fix *bold* C++ [link]' becomes '+Fix *bold* C+pass:[+]pass:[+]+ [link]+'
+func escapeAsciidoc(text string) string {
+	text = lineBreakReplacer.Replace(text)
+	if !strings.ContainsAny(text, asciidocSpecialChars) {
+		return text
+	}
+	segments := strings.Split(text, "+")
+	for i, s := range segments {
+		if s != "" {
+			segments[i] = "+" + s + "+"
+		}
+	}
+	return strings.Join(segments, "pass:[+]")
+}
+
+// the characters which have a meaning in the inline content of GitHub-flavoured markdown
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`(`, `\(`,
+	`)`, `\)`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`#`, `\#`,
+	`|`, `\|`,
+	`~`, `\~`,
+)
+
+// escapeMarkdown escapes the given text so that it is rendered as-is in GitHub-flavoured markdown
+// (eg: the title of an issue or pull request)
+func escapeMarkdown(text string) string {
+	return markdownReplacer.Replace(text)
+}
+
+// escapeHTML escapes the given text so that it is rendered as-is in HTML
+func escapeHTML(text string) string {
+	return template.HTMLEscapeString(text)
+}
+
+// the control characters of the Slack 'mrkdwn' format
+// (see https://api.slack.com/reference/surfaces/formatting#escaping)
+var slackReplacer = strings.NewReplacer(
+	`&`, `&amp;`,
+	`<`, `&lt;`,
+	`>`, `&gt;`,
+)
+
+// escapeSlack escapes the given text so that it is not interpreted as a link, a mention or a command
+// in the Slack 'mrkdwn' format. The formatting marks (eg: `*`, `_`) cannot be escaped in this format.
+func escapeSlack(text string) string {
+	return slackReplacer.Replace(text)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+)
+
+var update = flag.Bool("update", false, "updates the golden files in the 'testdata' directory")
+
+// titles with the special characters of all formats
+var escapeTestTitles = []string{
+	"Plain title",
+	`PR title ending \`,
+	`Fix the path C:\dir\`,
+	"Fix *bold* and _italic_ and `code`",
+	"Support C++ and a + b",
+	"+leading and trailing+",
+	"Link [text](http://example.com) and link:x[y] and [[anchor]]",
+	"Tag #hashtag# ^sup^ ~sub~ a|b",
+	"Attribute {attr} and {{ template }}",
+	`Quotes: it's "quoted"`,
+	"HTML <b>bold</b> & <script>alert(1)</script>",
+	"Slack <@U123> <!channel> <http://example.com|link>",
+	"Title on\ntwo lines",
+}
+
+func TestEscape(t *testing.T) {
+	for _, extension := range []string{"asciidoc", "markdown", "html", "slack"} {
+		t.Run(extension, func(t *testing.T) {
+			// given
+			escape := escapeFuncFor(extension)
+			// when
+			out := bytes.NewBuffer(nil)
+			for _, title := range escapeTestTitles {
+				fmt.Fprintf(out, "%q\n%s\n\n", title, escape(title))
+			}
+			// then
+			assertGolden(t, filepath.Join("testdata", "escape."+extension+".golden"), out.Bytes())
+		})
+	}
+}
+
+func TestEscapeUnknownExtension(t *testing.T) {
+	for _, title := range escapeTestTitles {
+		if escaped := escapeFuncFor("txt")(title); escaped != title {
+			t.Errorf("expected '%s' to be unchanged, got '%s'", title, escaped)
+		}
+	}
+}
+
+func TestEscapeAsciidocInHTMLReport(t *testing.T) {
+	// given
+	r := report.Report{
+		MergedPRs: []report.RepositoryPullRequests{
+			{
+				Repository: "o/a",
+			},
+		},
+	}
+	for i, title := range escapeTestTitles {
+		r.MergedPRs[0].PullRequests = append(r.MergedPRs[0].PullRequests, report.PullRequest{
+			Number: int64(i + 1),
+			Title:  title,
+			URL:    fmt.Sprintf("https://github.com/o/a/pull/%d", i+1),
+		})
+	}
+	// when the asciidoc report is converted to HTML
+	out := bytes.NewBuffer(nil)
+	if err := reportFormats["html"].render(context.Background(), out, r); err != nil {
+		t.Fatal(err)
+	}
+	// then all titles are rendered as-is
+	html := out.String()
+	for i, title := range escapeTestTitles {
+		expected := fmt.Sprintf(">%d</a>] %s</p>", i+1, template.HTMLEscapeString(lineBreakReplacer.Replace(title)))
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected the report to contain '%s'", expected)
+		}
+	}
+	for _, unexpected := range []string{"pass:", "<strong>", "<em>", "<code>", "<mark>", "<sup>", "<sub>", "<b>", "<script>"} {
+		if strings.Contains(html, unexpected) {
+			t.Errorf("expected the report not to contain '%s'", unexpected)
+		}
+	}
+	if t.Failed() {
+		t.Logf("report:\n%s", html)
+	}
+}
+
+// assertGolden compares the actual content with the content of the given golden file,
+// or updates the golden file if the tests are run with the `-update` flag
+func assertGolden(t *testing.T, filename string, actual []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(filename, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("the content does not match the golden file '%s' (run the tests with '-update' to update it)\nexpected:\n%s\nactual:\n%s", filename, expected, actual)
+	}
+}
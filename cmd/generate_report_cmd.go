@@ -1,112 +1,130 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"sort"
-	"strings"
-	"sync"
-	"text/template"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
-	"github.com/fabric8-services/fabric8-changelog/client/zenhub"
 
-	"github.com/bytesparadise/libasciidoc"
 	"github.com/fabric8-services/fabric8-changelog/client/github"
+	"github.com/fabric8-services/fabric8-changelog/report"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-var since string
+var since, until string
 var outputDir string
 var outputFormat string
+var templatePath string
+var sortOrder string
+var generatedAt string
+var workspace string
+var pipelines []string
 
 // NewGenerateReportCommand generates a new report
 func NewGenerateReportCommand() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "report",
-		Short: "Generate a report based with all merged pull-requests and all issues in progress or review/QA",
-		RunE:  generateReport,
+		Short: "Generate a report based with all merged pull-requests and all issues in the given pipelines (by default: in progress or review/QA)",
+		RunE:  terminateOnExit(generateReport),
 		Args:  cobra.ExactArgs(0),
 	}
 	c.Flags().StringSliceVarP(&repos, "repositories", "r", defaultRepos, "the repositories on which the milestone will be created")
-	c.Flags().StringVarP(&since, "since", "s", "", "the date after which PRs were merged (format: '2006-01-02')")
+	c.Flags().StringVarP(&since, "since", "s", "", "the date from which PRs were merged (format: '2006-01-02')")
+	c.Flags().StringVarP(&until, "until", "u", "", "the date until which PRs were merged, inclusive (format: '2006-01-02' - default: no limit)")
 	c.Flags().StringVarP(&outputDir, "output", "o", "tmp", "the output directory, or '-' for stdout")
-	c.Flags().StringVarP(&outputFormat, "format", "f", "html", "the output format ('asciidoc' or 'html' - default 'html')")
+	c.Flags().StringVarP(&outputFormat, "format", "f", "html", "the output format ('asciidoc', 'html', 'markdown', 'json' or 'yaml' - default 'html')")
+	c.Flags().StringVarP(&templatePath, "template", "t", "", "the template file, or the directory of templates with a 'report.<extension>' entry point, with which the report is rendered instead of the output format")
+	c.Flags().StringVar(&sortOrder, "sort", SortByMergedAt, "the order of the pull requests and issues of each repository ('merged-at', 'number', 'title', 'author' or 'label')")
+	c.Flags().StringVar(&generatedAt, "generated-at", "", "the time at which the report is generated, so that two reports generated from the same data are identical (format: '2006-01-02T15:04:05Z07:00' - default: now)")
+	c.Flags().StringSliceVarP(&pipelines, "pipelines", "p", defaultPipelines, "the ZenHub pipelines of the issues to include in the 'Currently working on' section, in order")
+	c.Flags().StringVarP(&workspace, "workspace", "w", "", "the ID of the ZenHub workspace whose board is used to find the pipeline of the issues (the data of each issue is used instead if empty, or for the issues which are not on the board)")
 
 	return c
 }
 
-var renderTmpl template.Template
-
-func init() {
-
-	renderTmpl = newTextTemplate("report",
-		`Done since last week:
-
-{{ range $name, $prs := .MergedPRs }}* {{ $name }}:
-{{ range $idx, $pr := $prs }}{{ with $pr }}** [{{ .Permalink }}[{{ .Number}}]] {{ .Title }}{{ end }}
-{{ end }}
-{{ end }}
-
-Currently working on:
-
-{{ range $name, $issues := .InProgressIssues }}* {{ $name }}:
-{{ range $idx, $issue := $issues }}{{ with $issue }}** [{{ .URL }}[{{ .Number}}]] {{ .Title }}{{ end }}
-{{ end }}
-{{ end }}
-`)
-}
-
 func generateReport(cmd *cobra.Command, args []string) error {
+	format, err := selectReportFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if err := checkSortOrder(sortOrder); err != nil {
+		return err
+	}
+	g := time.Now()
+	if generatedAt != "" {
+		if g, err = time.Parse(time.RFC3339, generatedAt); err != nil {
+			return errors.Wrap(err, "invalid value for the 'generated-at' time")
+		}
+	}
 	sort.Strings(repos)
 	s, err := time.Parse("2006-01-02", since)
 	if err != nil {
 		return errors.Wrap(err, "invalid value for the 'since' date")
 	}
+	u := time.Time{}
+	if until != "" {
+		if u, err = time.Parse("2006-01-02", until); err != nil {
+			return errors.Wrap(err, "invalid value for the 'until' date")
+		}
+		if u.Before(s) {
+			return errors.Errorf("the 'until' date (%s) is before the 'since' date (%s)", until, since)
+		}
+	}
 
-	mergedPRs := listMergedPRs(repos, s)
-	inProgressIssues := listIssuesInProgress(repos)
+	fetched := fetchReportData(commandCtx, repos, s, u)
+	mergedPRs, prResults := listMergedPRs(fetched)
+	inProgressIssues, issueResults := listIssuesInProgress(commandCtx, fetched)
+	results := append(prResults, issueResults...)
+	if err := checkInterrupted(commandCtx); err != nil {
+		// do not generate a report with the data of the repositories processed before the interruption
+		return summarize(cmd, results)
+	}
 
 	// output the final result
 	// generate
-	output, close, err := getOut(cmd, outputDir, time.Now().Format("2006-01-02"), outputFormat)
+	output, close, err := getOut(cmd, outputDir, g.Format("2006-01-02"), format.extension)
 	if err != nil {
 		return errors.Wrap(err, "failed to render report")
 	}
 
 	defer close()
-	data := struct {
-		MergedPRs        map[string]map[int64]PullRequest
-		InProgressIssues map[string]map[int64]MilestoneIssue
-	}{
+	r := report.Report{
+		Version:     report.Version,
+		GeneratedAt: g,
+		Window: report.Window{
+			Since: s,
+		},
 		MergedPRs:        mergedPRs,
 		InProgressIssues: inProgressIssues,
+		TotalPoints:      totalPoints(inProgressIssues),
+		Failures:         failedRepos(results),
 	}
-	if outputFormat == "html" {
-		tmpOut := bytes.NewBuffer(nil)
-		err = renderTmpl.Execute(tmpOut, data)
-		if err != nil {
-			return errors.Wrap(err, "failed to render report")
-		}
-		_, err = libasciidoc.ConvertToHTML(context.Background(), tmpOut, output)
-		if err != nil {
-			return errors.Wrap(err, "failed to render merged pull requests")
-		}
-	} else {
-		err = renderTmpl.Execute(output, data)
-		if err != nil {
-			return errors.Wrap(err, "failed to render report")
-		}
+	if !u.IsZero() {
+		r.Window.Until = &u
+	}
+	if err := format.render(commandCtx, output, r); err != nil {
+		return errors.Wrap(err, "failed to render report")
 	}
 
-	return nil
+	return summarize(cmd, results)
+}
+
+// selectReportFormat returns the output format of the report, given by the `--template` or `--format` flags
+func selectReportFormat(cmd *cobra.Command) (reportFormat, error) {
+	if templatePath == "" {
+		return reportFormatOf(outputFormat)
+	}
+	if cmd.Flags().Changed("format") {
+		return reportFormat{}, errors.New("the '--template' flag cannot be used together with the '--format' flag")
+	}
+	return customReportFormat(templatePath)
 }
 
 type closeFunc func() error
@@ -121,7 +139,7 @@ func newCloseFileFunc(c io.Closer) closeFunc {
 	}
 }
 
-func getOut(cmd *cobra.Command, outputDir, outputDate, outputFormat string) (io.Writer, closeFunc, error) {
+func getOut(cmd *cobra.Command, outputDir, outputDate, extension string) (io.Writer, closeFunc, error) {
 	if outputDir == "-" {
 		// outfile is STDOUT
 		return cmd.OutOrStdout(), defaultCloseFunc(), nil
@@ -134,351 +152,252 @@ func getOut(cmd *cobra.Command, outputDir, outputDate, outputFormat string) (io.
 		}
 	}
 	// outfile is specified in the command line
-	outfile, err := os.Create(fmt.Sprintf("%s/changelog-%s.%s", outputDir, outputDate, outputFormat))
+	outfile, err := os.Create(fmt.Sprintf("%s/changelog-%s.%s", outputDir, outputDate, extension))
 	if err != nil {
 		return nil, nil, err
 	}
 	return outfile, newCloseFileFunc(outfile), nil
 }
 
-var fetchPullRequestsTmpl template.Template
-
-func init() {
-
-	fetchPullRequestsTmpl = newTextTemplate("fetch PRs",
-		`{
-		"query": "query  {
-			repository(owner:\"{{ .Owner }}\", name:\"{{ .Name }}\") {
-				pullRequests(last:{{ .Last }}, states:[{{ .State }}], orderBy:{field:UPDATED_AT, direction:ASC}{{ if .Before }}, before:\"{{ .Before}}\"{{ end }}) {
-					pageInfo {
-						endCursor
-					}
-					nodes {
-						number
-						title
-						mergedAt
-						permalink
-						
-					}
-				}
-			}
-		}"
-	}`)
+// listMergedPRs returns the sorted pull requests merged in each repository, along with the result of each repository
+func listMergedPRs(data []*repoData) ([]report.RepositoryPullRequests, []repoResult) {
+	result := []report.RepositoryPullRequests{}
+	results := make([]repoResult, len(data))
+	for i, d := range data {
+		results[i] = repoResult{repo: d.repo, value: d.pulls, err: d.pullsErr}
+		if d.pullsErr != nil || len(d.pulls) == 0 {
+			continue
+		}
+		prs := make([]report.PullRequest, 0, len(d.pulls))
+		for _, pr := range d.pulls {
+			prs = append(prs, pr.toReport())
+		}
+		sortPullRequests(prs, sortOrder)
+		result = append(result, report.RepositoryPullRequests{
+			Repository:   d.repo,
+			PullRequests: prs,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Repository < result[j].Repository
+	})
+	return result, results
 }
 
-func listMergedPRs(repos []string, since time.Time) map[string]map[int64]PullRequest {
-	wg := sync.WaitGroup{}
-	result := make(map[string]map[int64]PullRequest)
-	for i, repo := range repos {
-		wg.Add(1)
-		// process in a go routine to parallelize the I/O tasks
-		go func(idx int, repo string) {
-			defer wg.Done()
-			remote := strings.Split(repo, "/")
-			if len(remote) != 2 {
-				log.Errorf("'%s' is not a valid GH repository (fornat: '<owner>/<name>')", repo)
-				return
-			}
-			// query the repo until no more data is needed
-			pulls, err := fetchPullRequests(remote[0], remote[1], "MERGED", since)
-			if err != nil {
-				log.Errorf("failed to fetch merged pull requests for %s: %v", repo, err)
-				return
-			}
-			if len(pulls) > 0 {
-				result[repo] = pulls
-			}
-		}(i, repo)
+// checkGraphqlErrors returns the error which prevents from using the data of the given field in a GraphQL response
+// (eg: repository not found, missing scope, invalid query). Errors on other fields are only logged, so that the
+// partial data in the response can still be used.
+func checkGraphqlErrors(err error, field string) error {
+	errs, ok := err.(github.GraphqlErrors)
+	if !ok {
+		return err
 	}
-	wg.Wait()
-	return result
+	if err := errs.ForPath(field); err != nil {
+		return err
+	}
+	log.WithError(errs).Warnf("GraphQL query returned partial data for '%s'", field)
+	return nil
 }
 
-const (
-	ghDateFormat = "2006-01-02T15:04:05Z"
-)
+// PullRequest the merged pull request
+type PullRequest struct {
+	Number    int64     `json:"number"`
+	Title     string    `json:"title"`
+	MergedAt  time.Time `json:"mergedAt"`
+	Permalink string    `json:"permalink"`
+	Author    Author    `json:"author"`
+	Labels    Labels    `json:"labels"`
+}
 
-func fetchPullRequests(owner, name, state string, since time.Time) (map[int64]PullRequest, error) {
-	before := ""
-	pulls := map[int64]PullRequest{}
-	for {
-		found := false
-		// subset, endCursor, err := listPullRequests(owner, name, state, before, 10)
-		queryBuf := bytes.NewBuffer(nil)
-		err := fetchPullRequestsTmpl.Execute(queryBuf, struct {
-			Owner  string
-			Name   string
-			State  string
-			Before string
-			Last   int
-		}{
-			Owner:  owner,
-			Name:   name,
-			State:  state,
-			Before: before,
-			Last:   10,
-		})
-		var response PullRequestsResponse
-		err = github.ExecuteGraphqlQuery(queryBuf.String(), &response)
-		if err != nil {
-			return pulls, errors.Wrapf(err, "unable to get list of merged pull requests")
-		}
+// toReport converts the pull request into its representation in the report
+func (pr PullRequest) toReport() report.PullRequest {
+	return report.PullRequest{
+		Number:   pr.Number,
+		Title:    pr.Title,
+		URL:      pr.Permalink,
+		MergedAt: pr.MergedAt,
+		Author:   pr.Author.Login,
+		Labels:   []string(pr.Labels),
+	}
+}
 
-		subset := []PullRequest{}
-		endCursor := response.Data.Repository.PullRequests.PageInfo.EndCursor
-		for _, pr := range response.Data.Repository.PullRequests.Nodes {
-			subset = append(subset, pr)
+// listIssuesInProgress lists the issues in the selected pipelines in each repository, along with the result of each repository
+func listIssuesInProgress(ctx context.Context, data []*repoData) ([]report.Pipeline, []repoResult) {
+	names := make([]string, len(data))
+	byRepo := make(map[string]*repoData, len(data))
+	for i, d := range data {
+		names[i] = d.repo
+		byRepo[d.repo] = d
+	}
+	results := forEachRepo(ctx, names, func(ctx context.Context, repo string) (interface{}, error) {
+		d := byRepo[repo]
+		if d.issuesErr != nil {
+			return nil, d.issuesErr
 		}
-
-		// need to iterate on the data in reverse order
-		for i := len(subset) - 1; i >= 0; i-- {
-			pr := subset[i]
-			mergedAt, err := time.Parse(ghDateFormat, pr.MergedAt)
-			if err != nil {
-				return pulls, errors.Wrapf(err, "failed to parse 'mergedAt' date '%s'", pr.MergedAt)
-			}
-			log.Debugf("processing %s merged at %s (valid: %t)", pr.Title, mergedAt, mergedAt.After(since))
-			// ignore last result when using the cursor, because the resultset contains the last item of the previous "page"
-			if _, found := pulls[pr.Number]; !found && mergedAt.After(since) {
-				pulls[pr.Number] = pr
-				found = true
-			}
+		repoID, issues := d.databaseID, d.issues
+		log.Debugf("repo '%s': %d", repo, repoID)
+		log.Debugf("repo issues: %s", spew.Sdump(issues))
+		// find the current pipeline and the estimate of each issue on ZenHub, from the board of the workspace (if any),
+		// and from the data of the issues which are not on the board
+		missing := issueNumbers(issues)
+		if workspace != "" {
+			missing = setPipelinesFromBoard(ctx, repoID, issues)
+		}
+		err := setPipelinesFromIssues(ctx, repoID, issues, missing)
+		if err == nil {
+			filterIssuesInPipelines(issues, pipelines)
+			// finally, retrieve the epics of the remaining issues
+			err = setEpics(ctx, repoID, issues)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list work-in-progress issues")
+		}
+		log.Debugf("WIP issues: %s", spew.Sdump(issues))
+		return issues, nil
+	})
+	result := make(map[string]map[int64]MilestoneIssue)
+	for _, r := range results {
+		if r.err != nil {
+			continue
 		}
-		// if none of the PR matched, then assume it's all done
-		if !found {
-			return pulls, nil
+		if issues := r.value.(map[int64]MilestoneIssue); len(issues) > 0 {
+			result[r.repo] = issues
 		}
-		before = endCursor
 	}
+	return groupByPipeline(result, pipelines), results
 }
 
-// Example response:
-// {
-// 	"data": {
-// 	  "repository": {
-// 		"pullRequests": {
-// 		  "pageInfo": {
-// 			"endCursor": "Y3Vyc29yOnYyOpHODZiInQ=="
-// 		  },
-// 		  "nodes": [
-// 			{
-// 			  "number": 709,
-// 			  "title": "Upgrade to go v11.1 for test-coverage CI",
-// 			  "mergedAt": "2018-11-01T07:30:04Z",
-// 			  "permalink": "https://github.com/fabric8-services/fabric8-auth/pull/709"
-// 			},
-// 			{
-// 			  "number": 710,
-// 			  "title": "Move back to centos go and disable gofmt check in coverage job",
-// 			  "mergedAt": "2018-11-05T02:44:39Z",
-// 			  "permalink": "https://github.com/fabric8-services/fabric8-auth/pull/710"
-// 			}
-// 		  ]
-// 		}
-// 	  }
-// 	}
-// }
-
-// PullRequestsResponse the response to the GraphQL query to list merged pull requests
-type PullRequestsResponse struct {
-	Data struct {
-		Repository struct {
-			PullRequests struct {
-				PageInfo struct {
-					EndCursor string `json:"endCursor"`
-				} `json:"pageInfo"`
-				Nodes []PullRequest `json:"nodes"`
-			} `json:"pullRequests"`
-		} `json:"repository"`
-	} `json:"data"`
-}
-
-// PullRequest the merged pull request
-type PullRequest struct {
-	Number    int64  `json:"number"`
-	Title     string `json:"title"`
-	MergedAt  string `json:"mergedAt"`
-	Permalink string `json:"permalink"`
+// totalPoints returns the sum of the estimates of the issues in all pipelines
+func totalPoints(pipelines []report.Pipeline) float64 {
+	total := 0.0
+	for _, p := range pipelines {
+		total += p.TotalPoints
+	}
+	return total
 }
 
-var fetchMilestoneIssuesTmpl template.Template
-
-func init() {
-	fetchMilestoneIssuesTmpl = newTextTemplate("fetch repo id", `{
-		"query": "query {
-			repository(owner:\"{{ .Org }}\", name:\"{{ .Name }}\") {
-				databaseId
-				milestones(states:OPEN, first:1, orderBy:{
-					field:DUE_DATE
-					direction: ASC
-				}) {
-					nodes {
-						title
-						issues(states:OPEN, first:2, orderBy:
-						{
-							field: UPDATED_AT
-							direction: DESC
-							
-						}{{ if .After }}
-						after:\"{{ .After}}\"
-						{{ end }}) {
-							pageInfo {
-								endCursor
-								hasNextPage
-							}
-							nodes {
-								number
-								title
-								url
-							}
-						}
-					}
+// groupByPipeline groups the issues of each repository by pipeline, in the given order of pipelines,
+// with the repositories sorted by name and their issues sorted in the order given with the `--sort` flag
+func groupByPipeline(issues map[string]map[int64]MilestoneIssue, pipelines []string) []report.Pipeline {
+	repos := make([]string, 0, len(issues))
+	for repo := range issues {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	result := make([]report.Pipeline, len(pipelines))
+	for i, p := range pipelines {
+		result[i] = report.Pipeline{
+			Name:         p,
+			Repositories: []report.RepositoryIssues{},
+		}
+		for _, repo := range repos {
+			r := report.RepositoryIssues{
+				Repository: repo,
+				Issues:     []report.Issue{},
+			}
+			for _, issue := range issues[repo] {
+				if issue.Pipeline != p {
+					continue
 				}
+				r.Issues = append(r.Issues, issue.toReport())
+				r.Points += issue.Estimate
 			}
-		}"
-	}`)
-
+			if len(r.Issues) == 0 {
+				continue
+			}
+			sortIssues(r.Issues, sortOrder)
+			result[i].Repositories = append(result[i].Repositories, r)
+			result[i].TotalPoints += r.Points
+		}
+	}
+	return result
 }
 
-func listIssuesInProgress(repos []string) map[string]map[int64]MilestoneIssue {
-	wg := sync.WaitGroup{}
-	result := make(map[string]map[int64]MilestoneIssue)
-	// allPRs := make([]interface{}, len(repos))
-	for i, repo := range repos {
-		wg.Add(1)
-		// process in a go routine to parallelize the I/O tasks
-		go func(idx int, repo string) {
-			defer wg.Done()
-			remote := strings.Split(repo, "/")
-			if len(remote) != 2 {
-				log.Errorf("'%s' is not a valid GH repository (fornat: '<owner>/<name>')", repo)
-				return
-			}
-			// first, retrieve the repository ID on GitHub
-			repoID, issues, err := fetchMilestoneIssues(remote[0], remote[1])
-			if err != nil {
-				log.Errorf("unable to list work-in-progress issues for repo '%s': %v", repo, err)
-				return
-			}
-			log.Debugf("repo '%s': %d", repo, repoID)
-			log.Debugf("repo issues: %s", spew.Sdump(issues))
-			// then fetch events for each issue on ZenHub
-			err = filterInProgressIssues(repoID, issues)
-			if err != nil {
-				log.Errorf("unable to list work-in-progress issues for repo '%s': %v", repo, err)
-				return
-			}
-			log.Debugf("WIP issues: %s", spew.Sdump(issues))
-			if len(issues) > 0 {
-				result[repo] = issues
-			}
+// MilestoneIssue the milestone issue
+type MilestoneIssue struct {
+	Number    int64     `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Author    Author    `json:"author"`
+	Assignees Assignees `json:"assignees"`
+	Labels    Labels    `json:"labels"`
+	// Pipeline the current pipeline of the issue on ZenHub
+	Pipeline string `json:"pipeline,omitempty"`
+	// Estimate the estimate of the issue on ZenHub, in story points (0 if not estimated)
+	Estimate float64 `json:"estimate,omitempty"`
+	// Epic the parent epic of the issue on ZenHub, if any
+	Epic *Epic `json:"epic,omitempty"`
+}
 
-		}(i, repo)
+// toReport converts the issue into its representation in the report
+func (i MilestoneIssue) toReport() report.Issue {
+	result := report.Issue{
+		Number:    i.Number,
+		Title:     i.Title,
+		URL:       i.URL,
+		Author:    i.Author.Login,
+		Assignees: []string(i.Assignees),
+		Labels:    []string(i.Labels),
+		Pipeline:  i.Pipeline,
+		Estimate:  i.Estimate,
+	}
+	if i.Epic != nil {
+		result.Epic = &report.Epic{
+			Number: i.Epic.Number,
+			URL:    i.Epic.URL,
+		}
 	}
-	wg.Wait()
-
 	return result
 }
 
-func fetchMilestoneIssues(org, name string) (int64, map[int64]MilestoneIssue, error) {
-	issues := map[int64]MilestoneIssue{}
-	var after string
-	var databaseID int64
-	for {
-		queryBuf := bytes.NewBuffer(nil)
-		err := fetchMilestoneIssuesTmpl.Execute(queryBuf, struct {
-			Org   string
-			Name  string
-			After string
-		}{
-			Org:   org,
-			Name:  name,
-			After: after,
-		})
-		// log.Debugf("repository ID graphql query: %s", queryBuf.String())
-		if err != nil {
-			return -1, issues, errors.Wrapf(err, "unable to get milestone issues")
-		}
+// Author the author of an issue or a pull request (empty if the account of the author was deleted)
+type Author struct {
+	Login string `json:"login"`
+}
 
-		var response MilestoneIssuesResponse
-		err = github.ExecuteGraphqlQuery(queryBuf.String(), &response)
-		if err != nil {
-			return -1, issues, errors.Wrapf(err, "unable to get milestone issues")
-		}
-		if log.GetLevel() == log.DebugLevel {
-			r, _ := json.Marshal(response)
-			log.Debugf("unmarshalled response: %s", string(r))
-		}
-		if len(response.Data.Repository.Milestones.Nodes) == 0 {
-			return -1, issues, errors.Wrapf(err, "unable to get current milestone")
-		}
-		databaseID = response.Data.Repository.DatabaseID
-		milestone := response.Data.Repository.Milestones.Nodes[0]
-		for _, issue := range milestone.Issues.Nodes {
-			issues[issue.Number] = issue
-		}
-		if !milestone.Issues.PageInfo.HasNextPage {
-			break
-		}
-		after = milestone.Issues.PageInfo.EndCursor
+// Assignees the logins of the users assigned to an issue
+type Assignees []string
+
+// UnmarshalJSON decodes the logins from the `assignees { nodes { login } }` connection of the GraphQL response
+func (a *Assignees) UnmarshalJSON(data []byte) error {
+	connection := struct {
+		Nodes []struct {
+			Login string `json:"login"`
+		} `json:"nodes"`
+	}{}
+	if err := json.Unmarshal(data, &connection); err != nil {
+		return err
 	}
-	return databaseID, issues, nil
+	*a = make(Assignees, len(connection.Nodes))
+	for i, n := range connection.Nodes {
+		(*a)[i] = n.Login
+	}
+	return nil
 }
 
-// example response:
-// {
-// 	"data": {
-// 	  "repository": {
-// 		"databaseId": 144640567,
-// 		"milestones": {
-// 		  "nodes": [
-// 			{
-// 			  "title": "Sprint 160",
-// 			  "issues": {
-// 				"pageInfo": {
-// 				  "endCursor": "Y3Vyc29yOnYyOpK5MjAxOS0wMS0xNFQxMjoyMjoyMCswMTowMM4XsXVm",
-// 				  "hasNextPage": false
-// 				},
-// 				"nodes": [
-// 					{
-// 						"number": 59,
-// 						"title": "Endpoint to obtain cluster info by API URL"
-//						"url": "https://github.com/fabric8-services/fabric8-cluster/issues/59"
-//   				}
-//				]
-// 			  }
-// 			}
-// 		  ]
-// 		}
-// 	  }
-// 	}
-//   }
-
-// MilestoneIssuesResponse the response wrapper for the milestone issues
-type MilestoneIssuesResponse struct {
-	Data struct {
-		Repository struct {
-			DatabaseID int64 `json:"databaseID"`
-			Milestones struct {
-				Nodes []struct {
-					Title  string `json:"title"`
-					Issues struct {
-						PageInfo struct {
-							EndCursor   string `json:"endCursor"`
-							HasNextPage bool   `json:"hasNextPage"`
-						} `json:"pageInfo"`
-						Nodes []MilestoneIssue `json:"nodes"`
-					} `json:"issues"`
-				} `json:"nodes"`
-			} `json:"milestones"`
-		} `json:"repository"`
-	} `json:"data"`
+// Labels the names of the labels of an issue or a pull request
+type Labels []string
+
+// UnmarshalJSON decodes the names from the `labels { nodes { name } }` connection of the GraphQL response
+func (l *Labels) UnmarshalJSON(data []byte) error {
+	connection := struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	}{}
+	if err := json.Unmarshal(data, &connection); err != nil {
+		return err
+	}
+	*l = make(Labels, len(connection.Nodes))
+	for i, n := range connection.Nodes {
+		(*l)[i] = n.Name
+	}
+	return nil
 }
 
-// MilestoneIssue the milestone issue
-type MilestoneIssue struct {
+// Epic the parent epic of an issue
+type Epic struct {
 	Number int64  `json:"number"`
-	Title  string `json:"title"`
 	URL    string `json:"url"`
 }
 
@@ -489,36 +408,113 @@ const (
 	ReviewQA string = "Review/QA"
 )
 
-func filterInProgressIssues(repoID int64, issues map[int64]MilestoneIssue) error {
-	// for each issue, check the most recent event on ZenHub to see if the it is in the `In Progress` pipeline
-	// https://api.zenhub.io/p1/repositories/144640567/issues/59/events
-	events := []IssueEvent{}
-	for number := range issues {
-		err := zenhub.QueryIssueEvents(repoID, number, &events)
+// the pipelines included in the report by default
+var defaultPipelines = []string{InProgress, ReviewQA}
+
+// setPipelinesFromBoard sets the current pipeline of the issues, using the ZenHub board of the repository
+// which is retrieved in a single call. Returns the numbers of the issues which are not on the board, or of all
+// the issues if the board could not be retrieved (eg: the repository is not in the workspace).
+func setPipelinesFromBoard(ctx context.Context, repoID int64, issues map[int64]MilestoneIssue) []int64 {
+	board, err := zenhubClient.GetBoard(ctx, workspace, repoID)
+	if err != nil {
+		log.WithError(err).Warnf("unable to retrieve the board of repository %d in workspace '%s', using the data of each issue instead", repoID, workspace)
+		return issueNumbers(issues)
+	}
+	onBoard := map[int64]bool{}
+	for _, p := range board.Pipelines {
+		for _, i := range p.Issues {
+			if issue, found := issues[i.IssueNumber]; found {
+				issue.Pipeline = p.Name
+				if i.Estimate != nil {
+					issue.Estimate = i.Estimate.Value
+				}
+				issues[i.IssueNumber] = issue
+				onBoard[i.IssueNumber] = true
+			}
+		}
+	}
+	missing := []int64{}
+	for _, number := range issueNumbers(issues) {
+		if !onBoard[number] {
+			log.Debugf("issue %d is not on the board of repository %d, using its data instead", number, repoID)
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
+
+// setPipelinesFromIssues sets the current pipeline and the estimate of the issues with the given numbers, using the
+// ZenHub data of each issue (when no workspace was specified, or for the issues which are not on the board)
+func setPipelinesFromIssues(ctx context.Context, repoID int64, issues map[int64]MilestoneIssue, numbers []int64) error {
+	// https://api.zenhub.io/p1/repositories/144640567/issues/59
+	for _, number := range numbers {
+		data, err := zenhubClient.GetIssue(ctx, repoID, number)
 		if err != nil {
 			return err
 		}
-		if len(events) == 0 {
-			// issue is untriagged
-			log.Debugf("issue %d' has not bee triaged yet", number)
-			delete(issues, number)
-			continue
+		issue := issues[number]
+		issue.Pipeline = data.Pipeline.Name
+		if data.Estimate != nil {
+			issue.Estimate = data.Estimate.Value
+		}
+		issues[number] = issue
+		if issue.Pipeline == "" {
+			log.Debugf("issue %d is not in any pipeline", number)
+		}
+	}
+	return nil
+}
+
+// issueNumbers returns the sorted numbers of the given issues
+func issueNumbers(issues map[int64]MilestoneIssue) []int64 {
+	numbers := make([]int64, 0, len(issues))
+	for number := range issues {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// setEpics sets the parent epic of the issues, among the epics of the repository
+func setEpics(ctx context.Context, repoID int64, issues map[int64]MilestoneIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	epics, err := zenhubClient.ListEpics(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	for _, e := range epics {
+		epic, err := zenhubClient.GetEpic(ctx, repoID, e.IssueNumber)
+		if err != nil {
+			return err
 		}
-		for _, e := range events {
-			if e.Type == "transferIssue" &&
-				!(e.ToPipeline.Name == InProgress || events[0].ToPipeline.Name == ReviewQA) {
-				log.Debugf("issue %d's last event is not a move in the 'In Progress' pipeline", number)
-				delete(issues, number)
+		for _, child := range epic.Issues {
+			if issue, found := issues[child.IssueNumber]; found && child.RepoID == repoID {
+				issue.Epic = &Epic{
+					Number: e.IssueNumber,
+					URL:    e.IssueURL,
+				}
+				issues[child.IssueNumber] = issue
 			}
 		}
 	}
 	return nil
 }
 
-// IssueEvent a single event for an issue on ZenHub
-type IssueEvent struct {
-	Type       string `json:"type"`
-	ToPipeline struct {
-		Name string `json:"name"`
-	} `json:"to_pipeline"`
+// filterIssuesInPipelines retains the issues which are in one of the given pipelines
+func filterIssuesInPipelines(issues map[int64]MilestoneIssue, pipelines []string) {
+	for number, issue := range issues {
+		retain := false
+		for _, p := range pipelines {
+			if issue.Pipeline == p {
+				retain = true
+				break
+			}
+		}
+		if !retain {
+			log.Debugf("issue %d is in pipeline '%s', which is not included in the report", number, issue.Pipeline)
+			delete(issues, number)
+		}
+	}
 }
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+	"github.com/fabric8-services/fabric8-changelog/testsupport"
+)
+
+// seedReportData adds the merged pull requests and the issues of the current milestone of the 'o/a' and 'o/b'
+// repositories, with the pipelines and estimates of the issues on ZenHub
+func seedReportData(gh *testsupport.GitHubServer, zh *testsupport.ZenHubServer) {
+	a := gh.AddRepository("o/a", 1)
+	a.AddMergedPullRequest(10, "Add the cluster info endpoint", time.Date(2019, 1, 10, 7, 30, 0, 0, time.UTC)).Author = "alice"
+	a.AddMergedPullRequest(11, "Fix the *bold* title", time.Date(2019, 1, 11, 8, 0, 0, 0, time.UTC))
+	a.AddMergedPullRequest(9, "Merged before the report", time.Date(2019, 1, 8, 8, 0, 0, 0, time.UTC))
+	sprint := a.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	a.AddIssue(1, "Endpoint to obtain cluster info", sprint).Assignees = []string{"bob"}
+	a.AddIssue(2, "Review the docs", sprint)
+	a.AddIssue(3, "Not started yet", sprint)
+	zh.MoveIssue(1, 1, InProgress)
+	zh.SetEstimate(1, 1, 3)
+	zh.MoveIssue(1, 2, InProgress)
+	zh.MoveIssue(1, 2, ReviewQA)
+	zh.SetEstimate(1, 2, 2)
+	zh.MoveIssue(1, 3, "Backlog")
+	b := gh.AddRepository("o/b", 2)
+	b.AddMergedPullRequest(5, "Bump the version", time.Date(2019, 1, 9, 0, 0, 0, 0, time.UTC))
+}
+
+func TestGenerateReportInMarkdown(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/b", "--since", "2019-01-09", "--output", "-", "--format", "markdown")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"- o/a:\n" +
+			fmt.Sprintf("  - [#10](%[1]s/o/a/pull/10) Add the cluster info endpoint\n", gh.URL) +
+			fmt.Sprintf("  - [#11](%[1]s/o/a/pull/11) Fix the \\*bold\\* title\n", gh.URL),
+		"- o/b:\n" +
+			fmt.Sprintf("  - [#5](%[1]s/o/b/pull/5) Bump the version\n", gh.URL),
+		"**In Progress (3 points)**\n\n" +
+			"- o/a (3 points):\n" +
+			fmt.Sprintf("  - [#1](%[1]s/o/a/issues/1) Endpoint to obtain cluster info (3 points) @bob\n", gh.URL),
+		"**Review/QA (2 points)**\n\n" +
+			"- o/a (2 points):\n" +
+			fmt.Sprintf("  - [#2](%[1]s/o/a/issues/2) Review the docs (2 points)\n", gh.URL),
+		"Total: 5 points",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected the report to contain:\n%s\nactual report:\n%s", e, out)
+		}
+	}
+	for _, unexpected := range []string{"Merged before the report", "Not started yet", "Warning"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("expected the report not to contain '%s':\n%s", unexpected, out)
+		}
+	}
+}
+
+func TestGenerateReportInJSON(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/b", "--since", "2019-01-09", "--until", "2019-01-10", "--output", "-", "--format", "json")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v\n%s", err, out)
+	}
+	if r.Version != report.Version || !r.Window.Since.Equal(time.Date(2019, 1, 9, 0, 0, 0, 0, time.UTC)) ||
+		r.Window.Until == nil || !r.Window.Until.Equal(time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected version or window: %d %+v", r.Version, r.Window)
+	}
+	if len(r.MergedPRs) != 2 || r.MergedPRs[0].Repository != "o/a" || r.MergedPRs[1].Repository != "o/b" {
+		t.Fatalf("unexpected merged pull requests: %+v", r.MergedPRs)
+	}
+	if prs := r.MergedPRs[0].PullRequests; len(prs) != 1 || prs[0].Number != 10 || prs[0].Author != "alice" ||
+		prs[0].URL != gh.URL+"/o/a/pull/10" || !prs[0].MergedAt.Equal(time.Date(2019, 1, 10, 7, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected pull requests in 'o/a': %+v", prs)
+	}
+	if len(r.InProgressIssues) != 2 || r.InProgressIssues[0].Name != InProgress || r.InProgressIssues[1].Name != ReviewQA {
+		t.Fatalf("unexpected pipelines: %+v", r.InProgressIssues)
+	}
+	inProgress := r.InProgressIssues[0]
+	if len(inProgress.Repositories) != 1 || len(inProgress.Repositories[0].Issues) != 1 || inProgress.TotalPoints != 3 {
+		t.Fatalf("unexpected issues in progress: %+v", inProgress)
+	}
+	if i := inProgress.Repositories[0].Issues[0]; i.Number != 1 || i.Pipeline != InProgress || i.Estimate != 3 ||
+		len(i.Assignees) != 1 || i.Assignees[0] != "bob" {
+		t.Fatalf("unexpected issue in progress: %+v", i)
+	}
+	if r.TotalPoints != 5 || len(r.Failures) != 0 {
+		t.Fatalf("unexpected total points or failures: %g %+v", r.TotalPoints, r.Failures)
+	}
+}
+
+func TestGenerateReportWithMissingRepository(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/missing", "--since", "2019-01-09", "--output", "-", "--format", "json")
+	// then the report is generated, but the command fails
+	if err == nil || err.Error() != "report failed on 1 of 2 repositories" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out[:strings.LastIndex(out, "}")+1]), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v\n%s", err, out)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].Repository != "o/missing" || len(r.MergedPRs) != 1 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+}
+
+func TestGenerateReportWithWorkspace(t *testing.T) {
+	// given a repository which is not in the workspace, and an issue which is not on the board
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	b := gh.Repository("o/b")
+	b.AddIssue(7, "Issue in a repository out of the workspace", b.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC)))
+	zh.MoveIssue(2, 7, InProgress)
+	zh.SetEstimate(2, 7, 5)
+	zh.RemoveFromWorkspace(2)
+	zh.RemoveFromBoard(1, 2)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/b", "--since", "2019-01-09", "--output", "-", "--format", "json", "--workspace", "ws")
+	// then the pipelines and estimates of these issues are obtained from ZenHub anyway
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v\n%s", err, out)
+	}
+	if len(r.InProgressIssues) != 2 {
+		t.Fatalf("unexpected pipelines: %+v", r.InProgressIssues)
+	}
+	inProgress, reviewQA := r.InProgressIssues[0], r.InProgressIssues[1]
+	if len(inProgress.Repositories) != 2 || inProgress.Repositories[0].Points != 3 ||
+		inProgress.Repositories[1].Repository != "o/b" || inProgress.Repositories[1].Points != 5 {
+		t.Fatalf("unexpected issues in progress: %+v", inProgress)
+	}
+	if len(reviewQA.Repositories) != 1 || len(reviewQA.Repositories[0].Issues) != 1 ||
+		reviewQA.Repositories[0].Issues[0].Number != 2 || reviewQA.TotalPoints != 2 {
+		t.Fatalf("unexpected issues in review: %+v", reviewQA)
+	}
+	if r.TotalPoints != 10 || len(r.Failures) != 0 {
+		t.Fatalf("unexpected total points or failures: %g %+v", r.TotalPoints, r.Failures)
+	}
+}
+
+func TestGenerateReportWithSingleRequestPerIssue(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/b", "--since", "2019-01-09", "--output", "-", "--format", "json")
+	// then the pipeline and the estimate of each issue are obtained in a single request,
+	// along with a request for the epics of the repository
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := zh.RequestCount(); count != 3+1 {
+		t.Fatalf("expected 4 requests on ZenHub, got %d", count)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v\n%s", err, out)
+	}
+	if r.TotalPoints != 5 {
+		t.Fatalf("unexpected total points: %g", r.TotalPoints)
+	}
+}
+
+func TestGenerateReportWithGeneratedAt(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			seedReportData(gh, zh)
+			args := []string{"report", "-r", "o/a,o/b", "--since", "2019-01-09", "--output", "-", "--format", format, "--generated-at", "2019-01-16T09:12:43Z"}
+			// when
+			first, err := runCommand(t, gh, zh, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := runCommand(t, gh, zh, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// then
+			if first != second {
+				t.Fatalf("expected identical reports:\n%s\n%s", first, second)
+			}
+			if !strings.Contains(first, "2019-01-16T09:12:43Z") {
+				t.Fatalf("expected the report to contain the time at which it was generated:\n%s", first)
+			}
+		})
+	}
+}
+
+func TestGenerateReportWithInvalidGeneratedAt(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	// when
+	_, err := runCommand(t, gh, zh, "report", "-r", "o/a", "--since", "2019-01-09", "--output", "-", "--generated-at", "2019-01-16")
+	// then
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid value for the 'generated-at' time") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,10 +1,9 @@
 package cmd
 
 import (
-	"sync"
-
-	"github.com/fabric8-services/fabric8-changelog/client/github"
+	"context"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -14,7 +13,7 @@ func NewMoveIssuesToMilestoneCmd() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "move-issues",
 		Short: "Move all open issues to a new milestones for all the given repositories",
-		RunE:  moveIssues,
+		RunE:  terminateOnExit(moveIssues),
 		Args:  cobra.ExactArgs(0),
 	}
 	c.Flags().StringVarP(&from, "from", "", "", "the milestone to move the issues from (ef: 'Sprint 123')")
@@ -25,35 +24,36 @@ func NewMoveIssuesToMilestoneCmd() *cobra.Command {
 var from, to string
 
 func moveIssues(cmd *cobra.Command, args []string) error {
-	wg := sync.WaitGroup{}
-	for _, repo := range repos {
-		wg.Add(1)
-		go func(repo string) {
-			defer wg.Done()
-			// first, we need to retrieve the milestone numbers, given their name
-			fromMilestone, err := github.FetchMilestone(repo, from)
-			if err != nil {
-				log.WithError(err).Errorf("unable to move issues in repository '%s'", repo)
-				return
-			}
-			// next, list all open issues in the "from" milestone
-			issues, err := github.FetchMilestoneIssues(repo, fromMilestone.Number)
+	results := forEachRepo(commandCtx, repos, func(ctx context.Context, repo string) (interface{}, error) {
+		// first, we need to retrieve the milestone numbers, given their name
+		fromMilestone, err := githubClient.FetchMilestone(ctx, repo, from)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to move issues")
+		}
+		// next, list all open issues in the "from" milestone
+		issues, err := githubClient.FetchMilestoneIssues(ctx, repo, fromMilestone.Number)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to move issues")
+		}
+		if len(issues) == 0 {
+			return nil, skipRepo("no open issue in milestone '%s'", from)
+		}
+		toMilestone, err := githubClient.FetchMilestone(ctx, repo, to)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to move issues")
+		}
+		for _, issue := range issues {
+			err := githubClient.MoveIssue(ctx, &issue, toMilestone)
 			if err != nil {
-				log.WithError(err).Errorf("unable to move issues in repository '%s'", repo)
-				return
+				return nil, errors.Wrapf(err, "unable to move issue %s", issue.URL)
 			}
-			toMilestone, err := github.FetchMilestone(repo, to)
-			for _, issue := range issues {
-				err := github.MoveIssue(&issue, toMilestone)
-				if err != nil {
-					log.WithError(err).Errorf("unable to move issues in repository '%s'", repo)
-					return
-				}
-				log.Infof("moved issue %s to milestone %s", issue.URL, toMilestone.URL)
-			}
-		}(repo)
-	}
-	wg.Wait()
+			log.Infof("moved issue %s to milestone %s", issue.URL, toMilestone.URL)
+		}
+		return issues, nil
+	})
 	log.Debug("done")
-	return nil
+	if err := summarize(cmd, results); err != nil {
+		return err
+	}
+	return checkNotAllSkipped(cmd, results)
 }
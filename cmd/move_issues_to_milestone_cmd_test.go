@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMoveIssues(t *testing.T) {
+	// given more issues than a single page of the GitHub API
+	gh, zh := newTestServers(t)
+	a := gh.AddRepository("o/a", 1)
+	sprint1 := a.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	sprint2 := a.AddMilestone("Sprint 2", time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC))
+	for i := int64(1); i <= 250; i++ {
+		a.AddIssue(i, fmt.Sprintf("issue %d", i), sprint1)
+	}
+	closed := a.AddIssue(251, "closed issue", sprint1)
+	closed.State = "closed"
+	other := a.AddIssue(252, "issue in no milestone", nil)
+	// when
+	_, err := runCommand(t, gh, zh, "move-issues", "-r", "o/a", "--from", "Sprint 1", "--to", "Sprint 2")
+	// then all open issues were moved
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved := a.IssuesInMilestone(sprint2); len(moved) != 250 {
+		t.Fatalf("expected 250 issues in 'Sprint 2', got %d", len(moved))
+	}
+	if remaining := a.IssuesInMilestone(sprint1); len(remaining) != 1 || remaining[0] != closed {
+		t.Fatalf("expected only the closed issue in 'Sprint 1', got %d issue(s)", len(remaining))
+	}
+	if other.Milestone != 0 {
+		t.Fatalf("the issue in no milestone was moved to milestone %d", other.Milestone)
+	}
+}
+
+func TestMoveIssuesFromEmptyMilestone(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	for _, r := range []string{"o/a", "o/b"} {
+		repo := gh.AddRepository(r, 1)
+		repo.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+		repo.AddMilestone("Sprint 2", time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC))
+	}
+	// when
+	_, err := runCommand(t, gh, zh, "move-issues", "-r", "o/a,o/b", "--from", "Sprint 1", "--to", "Sprint 2")
+	// then all repositories are skipped, and the command fails
+	if err == nil || err.Error() != "move-issues was skipped on all 2 repositories" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"context"
+	_ "embed" // used to embed the GraphQL queries
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/client/github"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// repoData the data of a repository fetched on GitHub for the report
+type repoData struct {
+	repo string
+	// the pull requests merged during the period of the report
+	pulls    map[int64]PullRequest
+	pullsErr error
+	// the database ID of the repository, which is also its ID on ZenHub
+	databaseID int64
+	// the open issues of the current milestone
+	issues    map[int64]MilestoneIssue
+	issuesErr error
+}
+
+// the maximum number of repositories whose data is fetched in a single GraphQL query
+const graphqlBatchSize = 10
+
+// the fragments of the GraphQL query to fetch the data of the report, with the fields of the issues and of the pull requests
+//
+//go:embed queries/fetch_report_data.graphql
+var fetchReportDataFragments string
+
+// fetchReportData fetches the merged pull requests and the issues of the current milestone of the given repositories,
+// in batches of repositories which belong to the same owner (so that the query can use the owner's token).
+// The returned data is in the same order as the repositories.
+func fetchReportData(ctx context.Context, repos []string, since, until time.Time) []*repoData {
+	data := make([]*repoData, len(repos))
+	batches := [][]*repoData{}
+	current := map[string]int{}
+	for i, repo := range repos {
+		data[i] = &repoData{
+			repo:   repo,
+			pulls:  map[int64]PullRequest{},
+			issues: map[int64]MilestoneIssue{},
+		}
+		owner := ownerOf(repo)
+		if owner == "" {
+			err := errors.Errorf("'%s' is not a valid GH repository (fornat: '<owner>/<name>')", repo)
+			data[i].pullsErr, data[i].issuesErr = err, err
+			continue
+		}
+		b, found := current[owner]
+		if !found || len(batches[b]) == graphqlBatchSize {
+			b = len(batches)
+			batches = append(batches, nil)
+			current[owner] = b
+		}
+		batches[b] = append(batches[b], data[i])
+	}
+	// each batch only updates the data of its own repositories
+	forEach(len(batches), func(idx int) {
+		fetchBatch(ctx, batches[idx], since, until)
+	})
+	return data
+}
+
+// ownerOf returns the owner of the given repository, or an empty string if the name of the repository is invalid
+func ownerOf(repo string) string {
+	remote := strings.Split(repo, "/")
+	if len(remote) != 2 || remote[0] == "" || remote[1] == "" {
+		return ""
+	}
+	return remote[0]
+}
+
+// batchPart the part of a batched query for a single repository, with the state of the pagination of its issues and pull requests
+type batchPart struct {
+	index int
+	// true if the query includes the next page of issues of the repository
+	issues bool
+	// true if the query includes the next page of merged pull requests of the repository
+	pulls       bool
+	data        *repoData
+	query       string
+	issuesAfter string
+	pullsAfter  string
+}
+
+// fetchBatch fetches the data of the given repositories, which belong to the same owner. The next pages of issues
+// and pull requests are fetched in subsequent queries, which only include the repositories which have more pages.
+func fetchBatch(ctx context.Context, batch []*repoData, since, until time.Time) {
+	parts := make([]*batchPart, len(batch))
+	for i, d := range batch {
+		parts[i] = &batchPart{
+			index:  i,
+			issues: true,
+			pulls:  true,
+			data:   d,
+			query:  mergedQuery(d.repo, since, until),
+		}
+	}
+	owner := ownerOf(batch[0].repo)
+	for round := 1; ; round++ {
+		pending := []*batchPart{}
+		for _, p := range parts {
+			if p.issues || p.pulls {
+				pending = append(pending, p)
+			}
+		}
+		if len(pending) == 0 {
+			return
+		}
+		response := map[string]json.RawMessage{}
+		err := executeBatch(ctx, owner, pending, &response)
+		errs, partial := err.(github.GraphqlErrors)
+		if err != nil && !partial {
+			for _, p := range pending {
+				p.fail(err)
+			}
+			return
+		}
+		logCost(owner, round, len(pending), response["rateLimit"])
+		for _, p := range pending {
+			if p.issues {
+				p.collectIssues(response, errs)
+			}
+			if p.pulls {
+				p.collectPulls(response, errs)
+			}
+		}
+	}
+}
+
+// executeBatch executes the query for the given parts of a batch
+func executeBatch(ctx context.Context, owner string, parts []*batchPart, response *map[string]json.RawMessage) error {
+	variables := map[string]interface{}{}
+	for _, p := range parts {
+		if p.issues {
+			remote := strings.Split(p.data.repo, "/")
+			variables[fmt.Sprintf("owner%d", p.index)] = remote[0]
+			variables[fmt.Sprintf("name%d", p.index)] = remote[1]
+			if p.issuesAfter != "" {
+				variables[fmt.Sprintf("issuesAfter%d", p.index)] = p.issuesAfter
+			}
+		}
+		if p.pulls {
+			variables[fmt.Sprintf("query%d", p.index)] = p.query
+			if p.pullsAfter != "" {
+				variables[fmt.Sprintf("pullsAfter%d", p.index)] = p.pullsAfter
+			}
+		}
+	}
+	return githubClient.ExecuteGraphqlQuery(ctx, github.GraphqlRequest{
+		Query:         fetchReportDataQuery(parts),
+		Variables:     variables,
+		OperationName: "FetchReportData",
+		Owner:         owner,
+	}, response)
+}
+
+// the field of the query with the issues of the current milestone of a repository, aliased as 'r<index>',
+// along with its variables
+const milestoneIssuesField = `
+  r%[1]d: repository(owner: $owner%[1]d, name: $name%[1]d) {
+    databaseId
+    milestones(states: OPEN, first: 1, orderBy: {field: DUE_DATE, direction: ASC}) {
+      nodes {
+        title
+        issues(states: OPEN, first: 100, orderBy: {field: UPDATED_AT, direction: DESC}, after: $issuesAfter%[1]d) {
+          pageInfo {
+            endCursor
+            hasNextPage
+          }
+          nodes {
+            ...MilestoneIssueFields
+          }
+        }
+      }
+    }
+  }`
+const milestoneIssuesVariables = "$owner%[1]d: String!, $name%[1]d: String!, $issuesAfter%[1]d: String"
+
+// the field of the query with the pull requests merged in a repository, aliased as 'p<index>', along with its variables
+const mergedPullsField = `
+  p%[1]d: search(type: ISSUE, query: $query%[1]d, first: 100, after: $pullsAfter%[1]d) {
+    issueCount
+    pageInfo {
+      endCursor
+      hasNextPage
+    }
+    nodes {
+      ...MergedPullRequestFields
+    }
+  }`
+const mergedPullsVariables = "$query%[1]d: String!, $pullsAfter%[1]d: String"
+
+// fetchReportDataQuery returns the query which fetches the data of the given parts of a batch, with aliased fields for the
+// issues and for the merged pull requests of each repository. These fields are not in the embedded fragments, since
+// the variables of each repository (and of its cursors) must have distinct names.
+func fetchReportDataQuery(parts []*batchPart) string {
+	variables := []string{}
+	fields := strings.Builder{}
+	for _, p := range parts {
+		if p.issues {
+			variables = append(variables, fmt.Sprintf(milestoneIssuesVariables, p.index))
+			fmt.Fprintf(&fields, milestoneIssuesField, p.index)
+		}
+		if p.pulls {
+			variables = append(variables, fmt.Sprintf(mergedPullsVariables, p.index))
+			fmt.Fprintf(&fields, mergedPullsField, p.index)
+		}
+	}
+	query := strings.Builder{}
+	query.WriteString("query FetchReportData(")
+	query.WriteString(strings.Join(variables, ", "))
+	query.WriteString(") {\n  rateLimit {\n    cost\n    remaining\n  }")
+	query.WriteString(fields.String())
+	query.WriteString("\n}\n\n")
+	query.WriteString(fetchReportDataFragments)
+	return query.String()
+}
+
+// logCost logs the cost of a query, as reported in the `rateLimit` field of the response
+func logCost(owner string, round, repos int, data json.RawMessage) {
+	if log.GetLevel() < log.DebugLevel || len(data) == 0 {
+		return
+	}
+	rateLimit := struct {
+		Cost      int `json:"cost"`
+		Remaining int `json:"remaining"`
+	}{}
+	if err := json.Unmarshal(data, &rateLimit); err != nil {
+		log.WithError(err).Debug("unable to decode the rate-limit of the GraphQL query")
+		return
+	}
+	log.Debugf("GraphQL query #%d for %d repositories of '%s' cost %d point(s), %d remaining", round, repos, owner, rateLimit.Cost, rateLimit.Remaining)
+}
+
+// fail records the given error for the issues and pull requests which were not completely fetched yet
+func (p *batchPart) fail(err error) {
+	if p.issues {
+		p.data.issuesErr = errors.Wrapf(err, "unable to get milestone issues")
+		p.issues = false
+	}
+	if p.pulls {
+		p.data.pullsErr = errors.Wrapf(err, "unable to get list of merged pull requests")
+		p.pulls = false
+	}
+}
+
+// collectIssues collects the issues in the `r<index>` field of the response, and checks if there are more pages
+func (p *batchPart) collectIssues(response map[string]json.RawMessage, errs github.GraphqlErrors) {
+	alias := fmt.Sprintf("r%d", p.index)
+	p.issues = false
+	if err := errs.ForPath(alias); err != nil {
+		p.data.issuesErr = errors.Wrapf(err, "unable to get milestone issues")
+		return
+	}
+	r := MilestoneIssuesRepository{}
+	if err := json.Unmarshal(response[alias], &r); err != nil {
+		p.data.issuesErr = errors.Wrapf(err, "unable to decode milestone issues")
+		return
+	}
+	if len(r.Milestones.Nodes) == 0 {
+		p.data.issuesErr = skipRepo("no open milestone in repository '%s'", p.data.repo)
+		return
+	}
+	p.data.databaseID = r.DatabaseID
+	milestone := r.Milestones.Nodes[0]
+	for _, issue := range milestone.Issues.Nodes {
+		p.data.issues[issue.Number] = issue
+	}
+	if milestone.Issues.PageInfo.HasNextPage {
+		p.issues = true
+		p.issuesAfter = milestone.Issues.PageInfo.EndCursor
+	}
+}
+
+// collectPulls collects the merged pull requests in the `p<index>` field of the response, and checks if there are more pages
+func (p *batchPart) collectPulls(response map[string]json.RawMessage, errs github.GraphqlErrors) {
+	alias := fmt.Sprintf("p%d", p.index)
+	p.pulls = false
+	if err := errs.ForPath(alias); err != nil {
+		p.data.pullsErr = errors.Wrapf(err, "unable to get list of merged pull requests")
+		return
+	}
+	search := PullRequestSearch{}
+	if err := json.Unmarshal(response[alias], &search); err != nil {
+		p.data.pullsErr = errors.Wrapf(err, "unable to decode merged pull requests")
+		return
+	}
+	for _, pr := range search.Nodes {
+		log.Debugf("found %s merged at %s", pr.Title, pr.MergedAt)
+		p.data.pulls[pr.Number] = pr
+	}
+	if search.PageInfo.HasNextPage {
+		p.pulls = true
+		p.pullsAfter = search.PageInfo.EndCursor
+	}
+}
+
+// the format of the dates in the search queries
+const searchDateFormat = "2006-01-02"
+
+// mergedQuery returns the search query for the pull requests merged in the given repository during the given period
+// (both dates are inclusive, and there is no upper bound if `until` is zero)
+// eg: 'repo:fabric8-services/fabric8-auth is:pr is:merged merged:2019-01-09..2019-01-15'
+func mergedQuery(repo string, since, until time.Time) string {
+	merged := ">=" + since.Format(searchDateFormat)
+	if !until.IsZero() {
+		merged = since.Format(searchDateFormat) + ".." + until.Format(searchDateFormat)
+	}
+	return fmt.Sprintf("repo:%s is:pr is:merged merged:%s", repo, merged)
+}
+
+// Example response (with a single repository):
+// {
+// 	"data": {
+// 	  "rateLimit": {
+// 		"cost": 1,
+// 		"remaining": 4998
+// 	  },
+// 	  "r0": {
+// 		"databaseId": 144640567,
+// 		"milestones": {
+// 		  "nodes": [
+// 			{
+// 			  "title": "Sprint 160",
+// 			  "issues": {
+// 				"pageInfo": {
+// 				  "endCursor": "Y3Vyc29yOnYyOpK5MjAxOS0wMS0xNFQxMjoyMjoyMCswMTowMM4XsXVm",
+// 				  "hasNextPage": false
+// 				},
+// 				"nodes": [
+// 				  {
+// 					"number": 59,
+// 					"title": "Endpoint to obtain cluster info by API URL",
+// 					"url": "https://github.com/fabric8-services/fabric8-cluster/issues/59",
+// 					"assignees": {
+// 					  "nodes": [
+// 						{
+// 						  "login": "xcoulon"
+// 						}
+// 					  ]
+// 					}
+// 				  }
+// 				]
+// 			  }
+// 			}
+// 		  ]
+// 		}
+// 	  },
+// 	  "p0": {
+// 		"issueCount": 1,
+// 		"pageInfo": {
+// 		  "endCursor": "Y3Vyc29yOjE=",
+// 		  "hasNextPage": false
+// 		},
+// 		"nodes": [
+// 		  {
+// 			"number": 60,
+// 			"title": "Add the cluster info endpoint",
+// 			"mergedAt": "2019-01-10T07:30:04Z",
+// 			"permalink": "https://github.com/fabric8-services/fabric8-cluster/pull/60"
+// 		  }
+// 		]
+// 	  }
+// 	}
+// }
+
+// MilestoneIssuesRepository the repository in the `r<index>` fields of the response to the GraphQL query, with the issues of its current milestone
+type MilestoneIssuesRepository struct {
+	DatabaseID int64 `json:"databaseId"`
+	Milestones struct {
+		Nodes []struct {
+			Title  string `json:"title"`
+			Issues struct {
+				PageInfo struct {
+					EndCursor   string `json:"endCursor"`
+					HasNextPage bool   `json:"hasNextPage"`
+				} `json:"pageInfo"`
+				Nodes []MilestoneIssue `json:"nodes"`
+			} `json:"issues"`
+		} `json:"nodes"`
+	} `json:"milestones"`
+}
+
+// PullRequestSearch the search results in the `p<index>` fields of the response to the GraphQL query, with the merged pull requests
+type PullRequestSearch struct {
+	IssueCount int `json:"issueCount"`
+	PageInfo   struct {
+		EndCursor   string `json:"endCursor"`
+		HasNextPage bool   `json:"hasNextPage"`
+	} `json:"pageInfo"`
+	Nodes []PullRequest `json:"nodes"`
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+)
+
+func TestFetchReportDataQuery(t *testing.T) {
+	// given the parts of a batch whose issues or pull requests were completely fetched
+	parts := []*batchPart{
+		{index: 0, issues: true, pulls: true},
+		{index: 1, issues: false, pulls: true},
+		{index: 2, issues: true, pulls: false},
+	}
+	// when
+	query := fetchReportDataQuery(parts)
+	// then the query only has the fields of the remaining pages, and each of its variables is declared and used once
+	declared := regexp.MustCompile(`\$(\w+): String`).FindAllStringSubmatch(query, -1)
+	used := regexp.MustCompile(`: \$(\w+)[,)]`).FindAllStringSubmatch(query, -1)
+	names := func(matches [][]string) string {
+		result := []string{}
+		for _, m := range matches {
+			result = append(result, m[1])
+		}
+		sort.Strings(result)
+		return strings.Join(result, " ")
+	}
+	expected := "issuesAfter0 issuesAfter2 name0 name2 owner0 owner2 pullsAfter0 pullsAfter1 query0 query1"
+	if names(declared) != expected || names(used) != expected {
+		t.Fatalf("unexpected variables:\ndeclared: %s\nused:     %s\nexpected: %s\nquery:\n%s", names(declared), names(used), expected, query)
+	}
+	for _, alias := range []string{"r0: repository", "p0: search", "p1: search", "r2: repository"} {
+		if !strings.Contains(query, alias) {
+			t.Errorf("expected the query to contain '%s':\n%s", alias, query)
+		}
+	}
+	for _, alias := range []string{"r1: repository", "p2: search"} {
+		if strings.Contains(query, alias) {
+			t.Errorf("expected the query not to contain '%s':\n%s", alias, query)
+		}
+	}
+	for _, f := range []string{"MilestoneIssueFields", "MergedPullRequestFields"} {
+		if !strings.Contains(query, "..."+f) || !strings.Contains(query, "fragment "+f+" on ") {
+			t.Errorf("expected the query to use and define the '%s' fragment:\n%s", f, query)
+		}
+	}
+	if strings.Count(query, "{") != strings.Count(query, "}") || strings.Count(query, "(") != strings.Count(query, ")") {
+		t.Fatalf("unbalanced query:\n%s", query)
+	}
+}
+
+func TestGenerateReportWithSeveralBatchesAndPages(t *testing.T) {
+	// given more repositories than fit in a single query, whose issues and pull requests need different numbers of pages
+	gh, zh := newTestServers(t)
+	pulls := map[string]int{"o/r00": 150, "o/r03": 0, "o/r04": 201, "o/r11": 230}
+	issues := map[string]int{"o/r00": 1, "o/r03": 250, "o/r07": 0, "o/r11": 120}
+	repos := []string{}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("o/r%02d", i)
+		repos = append(repos, name)
+		if _, found := pulls[name]; !found {
+			pulls[name] = 1
+		}
+		if _, found := issues[name]; !found {
+			issues[name] = 2
+		}
+		r := gh.AddRepository(name, int64(i+1))
+		for n := 1; n <= pulls[name]; n++ {
+			r.AddMergedPullRequest(int64(1000+n), fmt.Sprintf("Pull request %d", n), time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC))
+		}
+		sprint := r.AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+		for n := 1; n <= issues[name]; n++ {
+			r.AddIssue(int64(n), fmt.Sprintf("Issue %d", n), sprint)
+			zh.MoveIssue(int64(i+1), int64(n), InProgress)
+		}
+	}
+	// when
+	out, err := runCommand(t, gh, zh, "report", "-r", strings.Join(repos, ","), "--since", "2019-01-09", "--output", "-", "--format", "json")
+	// then all the pages of each repository were fetched
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.Report{}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("unable to decode the report: %v", err)
+	}
+	if len(r.Failures) != 0 || len(r.InProgressIssues) != 2 {
+		t.Fatalf("unexpected failures or pipelines: %+v %d", r.Failures, len(r.InProgressIssues))
+	}
+	actualPulls := map[string]int{}
+	for _, rp := range r.MergedPRs {
+		actualPulls[rp.Repository] = len(rp.PullRequests)
+	}
+	actualIssues := map[string]int{}
+	for _, ri := range r.InProgressIssues[0].Repositories {
+		actualIssues[ri.Repository] = len(ri.Issues)
+	}
+	for _, name := range repos {
+		if actualPulls[name] != pulls[name] {
+			t.Errorf("expected %d merged pull requests in '%s', got %d", pulls[name], name, actualPulls[name])
+		}
+		if actualIssues[name] != issues[name] {
+			t.Errorf("expected %d issues in progress in '%s', got %d", issues[name], name, actualIssues[name])
+		}
+	}
+}
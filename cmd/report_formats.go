@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // used to embed the report templates
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bytesparadise/libasciidoc"
+	"github.com/fabric8-services/fabric8-changelog/report"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// reportFormat an output format of the report
+type reportFormat struct {
+	// the extension of the output file
+	extension string
+	// render renders the report in the format
+	render renderFunc
+}
+
+// renderFunc the function which writes the report in a given format
+type renderFunc func(ctx context.Context, w io.Writer, r report.Report) error
+
+// the template of the report in the asciidoc format
+//
+//go:embed templates/report.asciidoc
+var asciidocReportTemplate string
+
+// the template of the report in the GitHub-flavoured markdown format
+//
+//go:embed templates/report.markdown
+var markdownReportTemplate string
+
+// the supported output formats of the report, by name
+var reportFormats map[string]reportFormat
+
+func init() {
+	asciidoc := newTextTemplate("report.asciidoc", asciidocReportTemplate, templateFuncs, escapeFuncMap(escapeAsciidoc))
+	markdown := newTextTemplate("report.markdown", markdownReportTemplate, templateFuncs, escapeFuncMap(escapeMarkdown))
+	reportFormats = map[string]reportFormat{
+		"asciidoc": {
+			extension: "asciidoc",
+			render:    renderTemplate(asciidoc),
+		},
+		"html": {
+			extension: "html",
+			render:    renderHTML(asciidoc),
+		},
+		"markdown": {
+			extension: "md",
+			render:    renderTemplate(markdown),
+		},
+		"json": {
+			extension: "json",
+			render:    renderJSON,
+		},
+		"yaml": {
+			extension: "yaml",
+			render:    renderYAML,
+		},
+	}
+}
+
+// reportFormatOf returns the output format with the given name, or an error if the format is not supported
+func reportFormatOf(name string) (reportFormat, error) {
+	if f, found := reportFormats[name]; found {
+		return f, nil
+	}
+	return reportFormat{}, errors.Errorf("unsupported output format '%s' (supported formats: %s)", name, strings.Join(reportFormatNames(), ", "))
+}
+
+// reportFormatNames returns the sorted names of the supported output formats
+func reportFormatNames() []string {
+	names := make([]string, 0, len(reportFormats))
+	for n := range reportFormats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// customReportFormat returns the output format which renders the report with the template in the given file, or with
+// the templates in the given directory, whose entry point is the `report.<extension>` file. The extension of the output
+// file is the extension of the template file (or of the entry point), which also selects the `escape` function
+// of the templates (eg: markdown escaping for a 'report.md' entry point).
+func customReportFormat(path string) (reportFormat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return reportFormat{}, errors.Wrapf(err, "unable to load template")
+	}
+	files := []string{path}
+	main := filepath.Base(path)
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return reportFormat{}, errors.Wrapf(err, "unable to load templates")
+		}
+		files, main = []string{}, ""
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			files = append(files, filepath.Join(path, e.Name()))
+			if strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())) == "report" {
+				if main != "" {
+					return reportFormat{}, errors.Errorf("several entry points in template directory '%s': '%s' and '%s'", path, main, e.Name())
+				}
+				main = e.Name()
+			}
+		}
+		if main == "" {
+			return reportFormat{}, errors.Errorf("no entry point in template directory '%s' (expected a 'report.<extension>' file)", path)
+		}
+	}
+	extension := strings.TrimPrefix(filepath.Ext(main), ".")
+	if extension == "" {
+		extension = "txt"
+	}
+	tmpl, err := template.New(main).Funcs(templateFuncs).Funcs(escapeFuncMap(escapeFuncFor(extension))).ParseFiles(files...)
+	if err != nil {
+		return reportFormat{}, errors.Wrapf(err, "unable to parse template")
+	}
+	return reportFormat{
+		extension: extension,
+		render:    renderTemplate(*tmpl),
+	}, nil
+}
+
+// renderTemplate returns a function which renders the report with the given template
+func renderTemplate(tmpl template.Template) renderFunc {
+	return func(ctx context.Context, w io.Writer, r report.Report) error {
+		return tmpl.Execute(w, r)
+	}
+}
+
+// renderHTML returns a function which renders the report with the given asciidoc template, then converts it into HTML
+func renderHTML(tmpl template.Template) renderFunc {
+	return func(ctx context.Context, w io.Writer, r report.Report) error {
+		tmp := bytes.NewBuffer(nil)
+		if err := tmpl.Execute(tmp, r); err != nil {
+			return err
+		}
+		_, err := libasciidoc.ConvertToHTML(ctx, tmp, w)
+		return err
+	}
+}
+
+// renderJSON writes the report in the JSON format
+func renderJSON(ctx context.Context, w io.Writer, r report.Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// renderYAML writes the report in the YAML format
+func renderYAML(ctx context.Context, w io.Writer, r report.Report) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	return enc.Close()
+}
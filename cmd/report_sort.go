@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+	"github.com/pkg/errors"
+)
+
+// the orders in which the pull requests and issues of each repository can be sorted in the report
+const (
+	// SortByMergedAt sorts by merge date, from the oldest to the most recent (issues are sorted by number)
+	SortByMergedAt string = "merged-at"
+	// SortByNumber sorts by number
+	SortByNumber string = "number"
+	// SortByTitle sorts by title, regardless of the case
+	SortByTitle string = "title"
+	// SortByAuthor sorts by login of the author
+	SortByAuthor string = "author"
+	// SortByLabel sorts by label (using the first of the labels in alphabetical order), with the unlabelled ones at the end
+	SortByLabel string = "label"
+)
+
+var sortOrders = []string{SortByMergedAt, SortByNumber, SortByTitle, SortByAuthor, SortByLabel}
+
+// checkSortOrder returns an error if the given sort order is not supported
+func checkSortOrder(order string) error {
+	for _, o := range sortOrders {
+		if o == order {
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported sort order '%s' (supported orders: %s)", order, strings.Join(sortOrders, ", "))
+}
+
+// sortKey the values by which a pull request or an issue can be sorted
+type sortKey struct {
+	number   int64
+	title    string
+	author   string
+	labels   []string
+	mergedAt time.Time
+}
+
+// less compares the given keys in the given order. Keys with the same value in this order are compared by number,
+// so that the result does not depend on the initial order of the pull requests or issues.
+func (k sortKey) less(other sortKey, order string) bool {
+	switch order {
+	case SortByMergedAt:
+		if !k.mergedAt.Equal(other.mergedAt) {
+			return k.mergedAt.Before(other.mergedAt)
+		}
+	case SortByTitle:
+		if t, o := strings.ToLower(k.title), strings.ToLower(other.title); t != o {
+			return t < o
+		}
+	case SortByAuthor:
+		if a, o := strings.ToLower(k.author), strings.ToLower(other.author); a != o {
+			return a < o
+		}
+	case SortByLabel:
+		if l, o := strings.ToLower(firstLabel(k.labels)), strings.ToLower(firstLabel(other.labels)); l != o {
+			// unlabelled pull requests and issues come last
+			if l == "" || o == "" {
+				return o == ""
+			}
+			return l < o
+		}
+	}
+	return k.number < other.number
+}
+
+// firstLabel returns the first of the given labels in alphabetical order, or an empty string if there is none
+func firstLabel(labels []string) string {
+	first := ""
+	for _, l := range labels {
+		if first == "" || strings.ToLower(l) < strings.ToLower(first) {
+			first = l
+		}
+	}
+	return first
+}
+
+// sortPullRequests sorts the given pull requests in the given order
+func sortPullRequests(prs []report.PullRequest, order string) {
+	sort.Slice(prs, func(i, j int) bool {
+		return pullRequestKey(prs[i]).less(pullRequestKey(prs[j]), order)
+	})
+}
+
+func pullRequestKey(pr report.PullRequest) sortKey {
+	return sortKey{
+		number:   pr.Number,
+		title:    pr.Title,
+		author:   pr.Author,
+		labels:   pr.Labels,
+		mergedAt: pr.MergedAt,
+	}
+}
+
+// sortIssues sorts the given issues in the given order
+func sortIssues(issues []report.Issue, order string) {
+	sort.Slice(issues, func(i, j int) bool {
+		return issueKey(issues[i]).less(issueKey(issues[j]), order)
+	})
+}
+
+func issueKey(i report.Issue) sortKey {
+	return sortKey{
+		number: i.Number,
+		title:  i.Title,
+		author: i.Author,
+		labels: i.Labels,
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+)
+
+// sortTestPullRequests returns pull requests which have the same merge date, title, author or first label as another one
+func sortTestPullRequests() []report.PullRequest {
+	day := func(d int) time.Time { return time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC) }
+	return []report.PullRequest{
+		{Number: 5, Title: "Fix the docs", Author: "bob", Labels: []string{"docs"}, MergedAt: day(11)},
+		{Number: 2, Title: "add endpoint", Author: "Alice", MergedAt: day(12)},
+		{Number: 4, Title: "Add endpoint", Author: "alice", Labels: []string{"enhancement", "API"}, MergedAt: day(10)},
+		{Number: 3, Title: "Bump version", Author: "carol", Labels: []string{"docs"}, MergedAt: day(11)},
+		{Number: 1, Title: "Update deps", Author: "bob", MergedAt: day(9)},
+	}
+}
+
+func TestSortPullRequests(t *testing.T) {
+	testcases := []struct {
+		order    string
+		expected []int64
+	}{
+		// #3 and #5 merged at the same time
+		{order: SortByMergedAt, expected: []int64{1, 4, 3, 5, 2}},
+		{order: SortByNumber, expected: []int64{1, 2, 3, 4, 5}},
+		// #2 and #4 have the same title, regardless of the case
+		{order: SortByTitle, expected: []int64{2, 4, 3, 5, 1}},
+		{order: SortByAuthor, expected: []int64{2, 4, 1, 5, 3}},
+		// #4 is sorted by its first label in alphabetical order ('API'), and the unlabelled #1 and #2 come last
+		{order: SortByLabel, expected: []int64{4, 3, 5, 1, 2}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.order, func(t *testing.T) {
+			// given
+			prs := sortTestPullRequests()
+			// when
+			sortPullRequests(prs, tc.order)
+			// then
+			actual := []int64{}
+			for _, pr := range prs {
+				actual = append(actual, pr.Number)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Fatalf("unexpected order: %v (expected %v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSortIssues(t *testing.T) {
+	testcases := []struct {
+		order    string
+		expected []int64
+	}{
+		// issues have no merge date
+		{order: SortByMergedAt, expected: []int64{1, 2, 3, 4}},
+		{order: SortByNumber, expected: []int64{1, 2, 3, 4}},
+		{order: SortByTitle, expected: []int64{3, 4, 1, 2}},
+		{order: SortByLabel, expected: []int64{4, 1, 2, 3}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.order, func(t *testing.T) {
+			// given
+			issues := []report.Issue{
+				{Number: 3, Title: "Add endpoint"},
+				{Number: 1, Title: "Fix the docs", Labels: []string{"bug"}},
+				{Number: 4, Title: "add endpoint", Labels: []string{"api"}},
+				{Number: 2, Title: "Update deps", Labels: []string{"Bug"}},
+			}
+			// when
+			sortIssues(issues, tc.order)
+			// then
+			actual := []int64{}
+			for _, i := range issues {
+				actual = append(actual, i.Number)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Fatalf("unexpected order: %v (expected %v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateReportWithSortOrder(t *testing.T) {
+	testcases := []struct {
+		args     []string
+		expected []int64
+	}{
+		// by merge date, by default
+		{args: []string{}, expected: []int64{12, 10, 11}},
+		{args: []string{"--sort", SortByNumber}, expected: []int64{10, 11, 12}},
+		{args: []string{"--sort", SortByTitle}, expected: []int64{10, 12, 11}},
+	}
+	for _, tc := range testcases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			// given a pull request merged before the ones with a lower number
+			gh, zh := newTestServers(t)
+			seedReportData(gh, zh)
+			gh.Repository("o/a").AddMergedPullRequest(12, "Bump the version", time.Date(2019, 1, 9, 12, 0, 0, 0, time.UTC))
+			// when
+			out, err := runCommand(t, gh, zh, append([]string{"report", "-r", "o/a", "--since", "2019-01-09", "--output", "-", "--format", "markdown"}, tc.args...)...)
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			positions := []int{}
+			for _, n := range tc.expected {
+				positions = append(positions, strings.Index(out, fmt.Sprintf("[#%d]", n)))
+			}
+			for i := 1; i < len(positions); i++ {
+				if positions[i-1] < 0 || positions[i-1] > positions[i] {
+					t.Fatalf("expected the pull requests in the order %v:\n%s", tc.expected, out)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateReportWithUnsupportedSortOrder(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	// when
+	_, err := runCommand(t, gh, zh, "report", "-r", "o/a", "--since", "2019-01-09", "--output", "-", "--sort", "date")
+	// then
+	if err == nil || err.Error() != "unsupported sort order 'date' (supported orders: merged-at, number, title, author, label)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	_ "embed" // used to embed the GraphQL queries
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/fabric8-services/fabric8-changelog/client/github"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewReposCommand returns a new command to manage the repositories on which the commands apply
+func NewReposCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "repos",
+		Short: "Manage the repositories on which the commands apply",
+	}
+	c.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the repositories on which the commands apply, as selected by the '--repositories', '--group' or '--org' flags",
+		RunE:  terminateOnExit(listRepos),
+		Args:  cobra.ExactArgs(0),
+	})
+	return c
+}
+
+func listRepos(cmd *cobra.Command, args []string) error {
+	for _, r := range repos {
+		fmt.Fprintln(cmd.OutOrStdout(), r)
+	}
+	return nil
+}
+
+// -----------------------------------------
+// repository discovery
+// -----------------------------------------
+
+// the selectors of the repositories to discover in organizations
+var orgs, topics, includes, excludes []string
+var skipArchived, skipForks, skipPrivate bool
+
+// initRepos replaces the repositories on which the command applies with the repositories discovered
+// in the organizations given with the `--org` flag, if any
+func initRepos(cmd *cobra.Command) error {
+	if len(orgs) == 0 {
+		for _, f := range []string{"topic", "include", "exclude"} {
+			if cmd.Flags().Changed(f) {
+				return errors.Errorf("the '--%s' flag requires the '--org' flag", f)
+			}
+		}
+		return nil
+	}
+	if cmd.Flags().Changed("repositories") || groupName != "" {
+		return errors.New("the '--org' flag cannot be used together with the '--repositories' or '--group' flags")
+	}
+	discovered, err := discoverRepos(commandCtx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to discover the repositories")
+	}
+	if len(discovered) == 0 {
+		return errors.Errorf("no repository matched the selectors in %v", orgs)
+	}
+	log.Debugf("discovered repositories: %v", discovered)
+	repos = discovered
+	return nil
+}
+
+// discoverRepos returns the sorted names of the repositories of the organizations which match the selectors
+func discoverRepos(ctx context.Context) ([]string, error) {
+	result := []string{}
+	for _, org := range orgs {
+		candidates, err := listOrganizationRepositories(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range candidates {
+			if ok, reason := selectRepo(r); !ok {
+				log.Debugf("skipping repository '%s': %s", r.NameWithOwner, reason)
+				continue
+			}
+			result = append(result, r.NameWithOwner)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// selectRepo checks if the given repository matches the selectors, and returns the reason why if it does not
+func selectRepo(r OrganizationRepository) (bool, string) {
+	switch {
+	case skipArchived && r.IsArchived:
+		return false, "archived"
+	case skipForks && r.IsFork:
+		return false, "fork"
+	case skipPrivate && r.IsPrivate:
+		return false, "private"
+	}
+	if len(topics) > 0 && !r.Topics.containsAny(topics) {
+		return false, fmt.Sprintf("none of the topics %v", topics)
+	}
+	if len(includes) > 0 && !matchAny(includes, r.Name) {
+		return false, fmt.Sprintf("name does not match %v", includes)
+	}
+	if matchAny(excludes, r.Name) {
+		return false, fmt.Sprintf("name matches %v", excludes)
+	}
+	return true, ""
+}
+
+// matchAny checks if the given name matches any of the given glob patterns (eg: 'fabric8-*-client')
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matched, _ := path.Match(p, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// the GraphQL query to list the repositories of an organization
+//
+//go:embed queries/list_organization_repositories.graphql
+var listOrganizationRepositoriesQuery string
+
+// listOrganizationRepositories lists all the repositories of the given organization
+func listOrganizationRepositories(ctx context.Context, org string) ([]OrganizationRepository, error) {
+	result := []OrganizationRepository{}
+	var after string
+	for {
+		variables := map[string]interface{}{
+			"org": org,
+		}
+		if after != "" {
+			variables["after"] = after
+		}
+		var response OrganizationRepositoriesResponse
+		err := checkGraphqlErrors(githubClient.ExecuteGraphqlQuery(ctx, github.GraphqlRequest{
+			Query:         listOrganizationRepositoriesQuery,
+			Variables:     variables,
+			OperationName: "ListOrganizationRepositories",
+			Owner:         org,
+		}, &response), "organization")
+		if err != nil {
+			return result, errors.Wrapf(err, "unable to list the repositories of organization '%s'", org)
+		}
+		result = append(result, response.Organization.Repositories.Nodes...)
+		if !response.Organization.Repositories.PageInfo.HasNextPage {
+			return result, nil
+		}
+		after = response.Organization.Repositories.PageInfo.EndCursor
+	}
+}
+
+// OrganizationRepositoriesResponse the data in the response to the GraphQL query to list the repositories of an organization
+type OrganizationRepositoriesResponse struct {
+	Organization struct {
+		Repositories struct {
+			PageInfo struct {
+				EndCursor   string `json:"endCursor"`
+				HasNextPage bool   `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			Nodes []OrganizationRepository `json:"nodes"`
+		} `json:"repositories"`
+	} `json:"organization"`
+}
+
+// OrganizationRepository a repository of an organization
+type OrganizationRepository struct {
+	NameWithOwner string `json:"nameWithOwner"`
+	Name          string `json:"name"`
+	IsArchived    bool   `json:"isArchived"`
+	IsFork        bool   `json:"isFork"`
+	IsPrivate     bool   `json:"isPrivate"`
+	Topics        Topics `json:"repositoryTopics"`
+}
+
+// Topics the names of the topics of a repository
+type Topics []string
+
+// UnmarshalJSON decodes the names from the `repositoryTopics { nodes { topic { name } } }` connection of the GraphQL response
+func (t *Topics) UnmarshalJSON(data []byte) error {
+	connection := struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	}{}
+	if err := json.Unmarshal(data, &connection); err != nil {
+		return err
+	}
+	*t = make(Topics, len(connection.Nodes))
+	for i, n := range connection.Nodes {
+		(*t)[i] = n.Topic.Name
+	}
+	return nil
+}
+
+// containsAny checks if any of the given topics is in the list
+func (t Topics) containsAny(topics []string) bool {
+	for _, topic := range t {
+		for _, other := range topics {
+			if topic == other {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-changelog/testsupport"
+)
+
+// seedOrganizations adds the repositories of the 'o' and 'p' organizations, with their topics and visibility
+func seedOrganizations(gh *testsupport.GitHubServer) {
+	gh.AddRepository("o/fabric8-auth", 1).Topics = []string{"auth", "go"}
+	gh.AddRepository("o/fabric8-auth-client", 2).Topics = []string{"auth"}
+	gh.AddRepository("o/fabric8-cluster", 3).Topics = []string{"go"}
+	gh.AddRepository("o/archived", 4).IsArchived = true
+	gh.AddRepository("o/fork", 5).IsFork = true
+	gh.AddRepository("o/private", 6).IsPrivate = true
+	gh.AddRepository("p/fabric8-toolchain", 7)
+}
+
+func TestListRepositoriesOfOrganizations(t *testing.T) {
+	testcases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			args:     []string{"--org", "o"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster", "o/private"},
+		},
+		{
+			name:     "several organizations",
+			args:     []string{"--org", "p,o", "--include", "fabric8-*"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster", "p/fabric8-toolchain"},
+		},
+		{
+			name:     "topic",
+			args:     []string{"--org", "o", "--topic", "auth"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client"},
+		},
+		{
+			name:     "any of the topics",
+			args:     []string{"--org", "o", "--topic", "auth,go"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster"},
+		},
+		{
+			name:     "include and exclude",
+			args:     []string{"--org", "o", "--include", "fabric8-*", "--exclude", "*-client"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-cluster"},
+		},
+		{
+			name:     "several excludes",
+			args:     []string{"--org", "o", "--exclude", "*-client", "--exclude", "fabric8-cluster"},
+			expected: []string{"o/fabric8-auth", "o/private"},
+		},
+		{
+			name:     "archived",
+			args:     []string{"--org", "o", "--skip-archived=false"},
+			expected: []string{"o/archived", "o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster", "o/private"},
+		},
+		{
+			name:     "forks",
+			args:     []string{"--org", "o", "--skip-forks=false"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster", "o/fork", "o/private"},
+		},
+		{
+			name:     "skip private",
+			args:     []string{"--org", "o", "--skip-private"},
+			expected: []string{"o/fabric8-auth", "o/fabric8-auth-client", "o/fabric8-cluster"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			seedOrganizations(gh)
+			// when
+			out, err := runCommand(t, gh, zh, append([]string{"repos", "list"}, tc.args...)...)
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := strings.Join(tc.expected, "\n") + "\n"; out != expected {
+				t.Fatalf("unexpected repositories:\n%s\nexpected:\n%s", out, expected)
+			}
+		})
+	}
+}
+
+func TestListRepositoriesOfOrganizationOnSeveralPages(t *testing.T) {
+	// given more repositories than fit in a single page
+	gh, zh := newTestServers(t)
+	for i := 1; i <= 150; i++ {
+		gh.AddRepository(fmt.Sprintf("o/repo-%03d", i), int64(i))
+	}
+	// when
+	out, err := runCommand(t, gh, zh, "repos", "list", "--org", "o")
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 150 || lines[149] != "o/repo-150" {
+		t.Fatalf("unexpected repositories: %d", len(lines))
+	}
+}
+
+func TestListRepositoriesWithInvalidSelectors(t *testing.T) {
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "topic without organization",
+			args:     []string{"-r", "o/fabric8-auth", "--topic", "auth"},
+			expected: "the '--topic' flag requires the '--org' flag",
+		},
+		{
+			name:     "organization and repositories",
+			args:     []string{"--org", "o", "-r", "o/fabric8-auth"},
+			expected: "the '--org' flag cannot be used together with the '--repositories' or '--group' flags",
+		},
+		{
+			name:     "no match",
+			args:     []string{"--org", "o", "--topic", "java"},
+			expected: "no repository matched the selectors in [o]",
+		},
+		{
+			name:     "unknown organization",
+			args:     []string{"--org", "missing"},
+			expected: "unable to discover the repositories: unable to list the repositories of organization 'missing'",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			seedOrganizations(gh)
+			// when
+			_, err := runCommand(t, gh, zh, append([]string{"repos", "list"}, tc.args...)...)
+			// then
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
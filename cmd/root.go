@@ -1,6 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/client/github"
+	"github.com/fabric8-services/fabric8-changelog/client/httprecord"
+	"github.com/fabric8-services/fabric8-changelog/client/zenhub"
+
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -8,20 +20,113 @@ import (
 // NewRootCommand initializes the root command
 func NewRootCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:              "fabric8-changelog",
-		Short:            "fabric8-changelog is a CLI tool to manage issues on GitHub and ZenHub",
-		PersistentPreRun: setLoggerLevel,
-		Args:             cobra.ExactArgs(1),
+		Use:               "fabric8-changelog",
+		Short:             "fabric8-changelog is a CLI tool to manage issues on GitHub and ZenHub",
+		PersistentPreRunE: initCommand,
+		Args:              cobra.ExactArgs(1),
 	}
 	c.PersistentFlags().StringSliceVarP(&repos, "repositories", "r", defaultRepos, "the repositories on which the command applies")
+	c.PersistentFlags().StringVarP(&groupName, "group", "g", "", "the group of repositories (defined in the configuration file) on which the command applies, instead of '--repositories'")
+	c.PersistentFlags().StringSliceVar(&orgs, "org", nil, "the organizations whose repositories the command applies on, instead of '--repositories'")
+	c.PersistentFlags().StringSliceVar(&topics, "topic", nil, "only the repositories of the organizations with at least one of the given topics")
+	c.PersistentFlags().StringSliceVar(&includes, "include", nil, "only the repositories of the organizations whose name matches one of the given patterns (eg: 'fabric8-*')")
+	c.PersistentFlags().StringSliceVar(&excludes, "exclude", nil, "excludes the repositories of the organizations whose name matches one of the given patterns (eg: '*-client')")
+	c.PersistentFlags().BoolVar(&skipArchived, "skip-archived", true, "excludes the archived repositories of the organizations")
+	c.PersistentFlags().BoolVar(&skipForks, "skip-forks", true, "excludes the forked repositories of the organizations")
+	c.PersistentFlags().BoolVar(&skipPrivate, "skip-private", false, "excludes the private repositories of the organizations")
+	c.PersistentFlags().StringVar(&configFile, "config", "", "the configuration file which defines the groups of repositories (default: '~/"+defaultConfigFile+"')")
 	c.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "prints the debug statements")
+	c.PersistentFlags().StringVar(&githubURL, "github-url", github.DefaultBaseURL, "the base URL of the GitHub Rest API (eg: 'https://github.example.com/api/v3' for GitHub Enterprise)")
+	c.PersistentFlags().StringVar(&githubGraphqlURL, "github-graphql-url", github.DefaultGraphqlURL, "the URL of the GitHub GraphQL API (eg: 'https://github.example.com/api/graphql' for GitHub Enterprise)")
+	c.PersistentFlags().StringVar(&githubToken, "github-token", "", "the token to access the GitHub API (default: '$GITHUB_TOKEN')")
+	c.PersistentFlags().Int64Var(&githubAppID, "github-app-id", 0, "the ID of the GitHub App to authenticate as (instead of using a personal token)")
+	c.PersistentFlags().StringVar(&githubAppKey, "github-app-key", "", "the path to the private key file of the GitHub App")
+	c.PersistentFlags().StringVar(&zenhubURL, "zenhub-url", zenhub.DefaultBaseURL, "the base URL of the ZenHub API (eg: 'https://zenhub.example.com/api' for ZenHub Enterprise)")
+	c.PersistentFlags().StringVar(&zenhubToken, "zenhub-token", "", "the token to access the ZenHub API (default: '$ZENHUB_TOKEN')")
+	c.PersistentFlags().IntVar(&zenhubRequestsPerMinute, "zenhub-rate-limit", 100, "the maximum number of requests per minute on the ZenHub API")
+	c.PersistentFlags().StringVar(&recordDir, "record", "", "the directory in which all requests and responses on GitHub and ZenHub are recorded")
+	c.PersistentFlags().StringVar(&replayDir, "replay", "", "the directory from which the responses recorded with '--record' are replayed, without accessing the network")
+	c.PersistentFlags().IntVar(&maxPages, "max-pages", 0, "the maximum number of pages to fetch on GitHub list endpoints (no limit if 0)")
+	c.PersistentFlags().IntVar(&maxRetries, "max-retries", 5, "the maximum number of retries for requests which failed with a transient error or a secondary rate-limit")
+	c.PersistentFlags().IntVar(&concurrency, "concurrency", 5, "the maximum number of repositories processed at the same time")
+	c.PersistentFlags().DurationVar(&timeout, "timeout", 0, "the maximum duration of the command, after which all pending requests are cancelled (no limit if 0)")
 	c.AddCommand(NewGenerateReportCommand())
 	c.AddCommand(NewCreateMilestoneCmd())
 	c.AddCommand(NewMoveIssuesToMilestoneCmd())
 	c.AddCommand(NewCloseMilestoneCmd())
+	c.AddCommand(NewReposCommand())
 	return c
 }
 
+func initCommand(cmd *cobra.Command, args []string) (err error) {
+	setLoggerLevel(cmd, args)
+	if err := initGroup(cmd); err != nil {
+		return err
+	}
+	githubTransport = nil
+	commandCtx, cancelCommand = newCommandContext()
+	defer func() {
+		// the command is not run if it could not be initialized
+		if err != nil {
+			terminateCommand(cmd, args)
+		}
+	}()
+	transport, err := newBaseTransport()
+	if err != nil {
+		return err
+	}
+	initZenHubClient(transport)
+	if err := initGitHubClient(transport); err != nil {
+		return err
+	}
+	return initRepos(cmd)
+}
+
+// terminateOnExit wraps the given function of a command, so that the command is terminated when the function returns,
+// including with an error (in which case the post-run functions of the command are not called)
+func terminateOnExit(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		defer terminateCommand(cmd, args)
+		return run(cmd, args)
+	}
+}
+
+// terminateCommand cancels the context of the command, and logs the usage of the GitHub API
+func terminateCommand(cmd *cobra.Command, args []string) {
+	if cancelCommand != nil {
+		cancelCommand()
+	}
+	logUsage(cmd, args)
+}
+
+// -----------------------------------------
+// cancellation and concurrency
+// -----------------------------------------
+
+// the maximum number of repositories processed at the same time
+var concurrency int
+
+// the maximum duration of the command
+var timeout time.Duration
+
+// the context passed to all requests on GitHub and ZenHub, which is cancelled when the command is interrupted
+// or when it timed out
+var commandCtx = context.Background()
+var cancelCommand context.CancelFunc
+
+// newCommandContext returns a new context which is cancelled on Ctrl-C (or SIGTERM), or when the timeout is reached
+func newCommandContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
 // -----------------------------------------
 // repositories on which the command applies
 // -----------------------------------------
@@ -63,3 +168,99 @@ func setLoggerLevel(cmd *cobra.Command, args []string) {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 }
+
+// -----------------------------------------
+// GitHub client
+// -----------------------------------------
+
+var githubURL, githubGraphqlURL, githubToken, githubAppKey string
+var githubAppID int64
+var maxRetries, maxPages int
+
+// the transport shared by all requests on the GitHub API, which keeps track of the rate-limit budget
+var githubTransport *github.RateLimitTransport
+
+// the client shared by all commands to access the GitHub API
+var githubClient *github.Client
+
+func initGitHubClient(base http.RoundTripper) error {
+	githubTransport = github.NewRateLimitTransport(base, maxRetries, time.Second)
+	httpClient := &http.Client{Transport: githubTransport}
+	var tokens github.TokenSource
+	if githubAppID != 0 {
+		// authenticate as a GitHub App installation
+		appTokens, err := github.NewAppTokenSource(githubAppID, githubAppKey, githubURL, httpClient)
+		if err != nil {
+			return errors.Wrap(err, "unable to initialize the GitHub client")
+		}
+		tokens = appTokens
+	} else {
+		if githubToken == "" {
+			githubToken = os.Getenv("GITHUB_TOKEN")
+		}
+		tokens = github.StaticTokenSource(githubToken)
+	}
+	githubClient = github.NewClient(github.Config{
+		BaseURL:    githubURL,
+		GraphqlURL: githubGraphqlURL,
+		HTTPClient: httpClient,
+		Tokens:     tokens,
+		MaxPages:   maxPages,
+	})
+	return nil
+}
+
+func logUsage(cmd *cobra.Command, args []string) {
+	if githubTransport != nil {
+		logrus.Infof("GitHub API usage: %s", githubTransport.Usage())
+	}
+}
+
+// -----------------------------------------
+// ZenHub client
+// -----------------------------------------
+
+var zenhubURL, zenhubToken string
+var zenhubRequestsPerMinute int
+
+// the client shared by all commands to access the ZenHub API
+var zenhubClient *zenhub.Client
+
+func initZenHubClient(base http.RoundTripper) {
+	if zenhubToken == "" {
+		zenhubToken = os.Getenv("ZENHUB_TOKEN")
+	}
+	transport := base
+	if replayDir == "" {
+		// no need to pace the requests when the responses are replayed
+		transport = zenhub.NewRateLimitTransport(base, zenhubRequestsPerMinute, maxRetries)
+	}
+	zenhubClient = zenhub.NewClient(zenhub.Config{
+		BaseURL:    zenhubURL,
+		HTTPClient: &http.Client{Transport: transport},
+		Token:      zenhubToken,
+	})
+}
+
+// -----------------------------------------
+// record/replay
+// -----------------------------------------
+
+var recordDir, replayDir string
+
+// newBaseTransport returns the transport on which the requests on GitHub and ZenHub are sent,
+// which records or replays the requests and responses if needed
+func newBaseTransport() (http.RoundTripper, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, errors.New("the '--record' and '--replay' flags cannot be used together")
+	case recordDir != "":
+		logrus.Infof("recording requests and responses in '%s'", recordDir)
+		return httprecord.NewRecorder(recordDir, http.DefaultTransport)
+	case replayDir != "":
+		logrus.Infof("replaying responses from '%s'", replayDir)
+		return httprecord.NewReplayer(replayDir)
+	default:
+		return http.DefaultTransport, nil
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/testsupport"
+)
+
+// newTestServers starts the fake GitHub and ZenHub servers, which are closed at the end of the test
+func newTestServers(t *testing.T) (*testsupport.GitHubServer, *testsupport.ZenHubServer) {
+	gh := testsupport.NewGitHubServer()
+	t.Cleanup(gh.Close)
+	zh := testsupport.NewZenHubServer()
+	t.Cleanup(zh.Close)
+	return gh, zh
+}
+
+// runCommand executes the root command with the given arguments against the fake servers (and with an empty
+// configuration file, unless another one is given), and returns the output of the command along with its error
+func runCommand(t *testing.T, gh *testsupport.GitHubServer, zh *testsupport.ZenHubServer, args ...string) (string, error) {
+	t.Helper()
+	out := bytes.NewBuffer(nil)
+	c := NewRootCommand()
+	c.SetOutput(out)
+	if !contains(args, "--config") {
+		args = append(args, "--config", "testdata/empty-config.yaml")
+	}
+	c.SetArgs(append(args,
+		"--github-url", gh.URL,
+		"--github-graphql-url", gh.GraphqlURL(),
+		"--github-token", "github-token",
+		"--zenhub-url", zh.URL,
+		"--zenhub-token", "zenhub-token",
+		"--zenhub-rate-limit", "0",
+	))
+	err := c.Execute()
+	return out.String(), err
+}
+
+func TestTerminateCommandOnError(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	gh.AddRepository("o/a", 1).AddMilestone("Sprint 1", time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC))
+	// when
+	_, err := runCommand(t, gh, zh, "close-milestone", "-r", "o/a,o/missing", "--name", "Sprint 1")
+	// then the context of the command was cancelled, even though the command failed
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if commandCtx.Err() != context.Canceled {
+		t.Fatalf("the context of the command was not cancelled: %v", commandCtx.Err())
+	}
+}
+
+func TestTerminateCommandOnInitializationError(t *testing.T) {
+	// given
+	gh, zh := newTestServers(t)
+	// when
+	_, err := runCommand(t, gh, zh, "close-milestone", "-r", "o/a", "--name", "Sprint 1", "--record", "fixtures", "--replay", "fixtures")
+	// then
+	if err == nil || err.Error() != "the '--record' and '--replay' flags cannot be used together" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if commandCtx.Err() != context.Canceled {
+		t.Fatalf("the context of the command was not cancelled: %v", commandCtx.Err())
+	}
+}
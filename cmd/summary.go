@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// skipError the error returned when a repository was skipped because there was nothing to do
+// (eg: the milestone to close does not exist, or is already closed)
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+// skipRepo returns an error to skip the current repository, for the given reason
+func skipRepo(format string, args ...interface{}) error {
+	return skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+// the status of a repository once processed
+const (
+	succeeded = "succeeded"
+	skipped   = "skipped"
+	failed    = "failed"
+)
+
+// status returns the status of the repository ('succeeded', 'skipped' or 'failed')
+func (r repoResult) status() string {
+	switch errors.Cause(r.err).(type) {
+	case nil:
+		return succeeded
+	case skipError:
+		return skipped
+	default:
+		return failed
+	}
+}
+
+// repoStatus the aggregated status of a repository, along with the reasons why it was skipped or why it failed
+type repoStatus struct {
+	repo    string
+	status  string
+	reasons []string
+}
+
+// aggregate aggregates the results per repository, in the order in which the repositories were first seen.
+// A repository which was processed several times (eg: once for the pull requests and once for the issues of the report)
+// has the "worst" status of all its results.
+func aggregate(results []repoResult) []repoStatus {
+	rank := map[string]int{succeeded: 0, skipped: 1, failed: 2}
+	statuses := []repoStatus{}
+	index := map[string]int{}
+	for _, r := range results {
+		i, found := index[r.repo]
+		if !found {
+			i = len(statuses)
+			index[r.repo] = i
+			statuses = append(statuses, repoStatus{repo: r.repo, status: succeeded})
+		}
+		s := r.status()
+		if rank[s] > rank[statuses[i].status] {
+			statuses[i].status = s
+		}
+		if r.err != nil && !contains(statuses[i].reasons, r.err.Error()) {
+			statuses[i].reasons = append(statuses[i].reasons, r.err.Error())
+		}
+	}
+	return statuses
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// failedRepos returns the repositories which failed, along with the reasons why they failed
+func failedRepos(results []repoResult) []report.Failure {
+	failures := []report.Failure{}
+	for _, s := range aggregate(results) {
+		if s.status == failed {
+			failures = append(failures, report.Failure{
+				Repository: s.repo,
+				Reason:     strings.Join(s.reasons, "; "),
+			})
+		}
+	}
+	return failures
+}
+
+// summarize logs the status of each repository on which the command applied, and returns an error
+// if the command was interrupted or if any repository failed (so that the command exits with a non-zero code)
+func summarize(cmd *cobra.Command, results []repoResult) error {
+	statuses := aggregate(results)
+	counts := map[string]int{}
+	for _, s := range statuses {
+		counts[s.status]++
+	}
+	log.Infof("%s: %d repositories succeeded, %d skipped, %d failed", cmd.Name(), counts[succeeded], counts[skipped], counts[failed])
+	for _, s := range statuses {
+		switch s.status {
+		case succeeded:
+			log.Infof("  %s: %s", s.repo, s.status)
+		case skipped:
+			log.Warnf("  %s: %s (%s)", s.repo, s.status, strings.Join(s.reasons, "; "))
+		default:
+			log.Errorf("  %s: %s (%s)", s.repo, s.status, strings.Join(s.reasons, "; "))
+		}
+	}
+	// the usage of the command is not relevant when some repositories failed
+	cmd.SilenceUsage = true
+	if err := checkInterrupted(commandCtx); err != nil {
+		return err
+	}
+	if counts[failed] > 0 {
+		return errors.Errorf("%s failed on %d of %d repositories", cmd.Name(), counts[failed], len(statuses))
+	}
+	return nil
+}
+
+// checkNotAllSkipped returns an error if all repositories were skipped, for the commands which are expected to apply
+// on at least one repository (eg: closing a milestone whose name was mistyped does not close anything)
+func checkNotAllSkipped(cmd *cobra.Command, results []repoResult) error {
+	statuses := aggregate(results)
+	if len(statuses) == 0 {
+		return nil
+	}
+	for _, s := range statuses {
+		if s.status != skipped {
+			return nil
+		}
+	}
+	return errors.Errorf("%s was skipped on all %d repositories", cmd.Name(), len(statuses))
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// templateFuncs the functions available in the report templates, including the templates given with the `--template` flag
+var templateFuncs = template.FuncMap{
+	"date":           date,
+	"pluralize":      pluralize,
+	"truncate":       truncate,
+	"join":           join,
+	"sortBy":         sortBy,
+	"groupByLabel":   groupByLabel,
+	"escapeAsciidoc": escapeAsciidoc,
+	"escapeMarkdown": escapeMarkdown,
+	"escapeHTML":     escapeHTML,
+	"escapeSlack":    escapeSlack,
+}
+
+// date formats the given time (a `time.Time` or a `*time.Time`) with the given layout
+// eg: `{{ .MergedAt | date "Jan 2" }}`
+func date(layout string, t interface{}) (string, error) {
+	switch t := t.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	default:
+		return "", errors.Errorf("cannot format value of type %T as a date", t)
+	}
+}
+
+// pluralize returns the singular form if the given count is 1, the plural form otherwise
+// eg: `{{ .TotalPoints }} {{ pluralize .TotalPoints "point" "points" }}`
+func pluralize(count interface{}, singular, plural string) (string, error) {
+	c, err := toFloat(reflect.ValueOf(count))
+	if err != nil {
+		return "", err
+	}
+	if c == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+// truncate shortens the given text to the given number of characters, with an ellipsis at the end if it was truncated
+// eg: `{{ .Title | truncate 50 }}`
+func truncate(length int, text string) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	if length <= 0 {
+		return ""
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+// join joins the elements of the given slice with the given separator
+// eg: `{{ .Assignees | join ", " }}`
+func join(sep string, items interface{}) (string, error) {
+	if s, ok := items.([]string); ok {
+		return strings.Join(s, sep), nil
+	}
+	values, err := valuesOf(items)
+	if err != nil {
+		return "", err
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprint(v.Interface())
+	}
+	return strings.Join(s, sep), nil
+}
+
+// sortBy returns the elements of the given slice, or the values of the given map, sorted by the given field,
+// or in the reverse order if the name of the field starts with '-'
+// eg: `{{ range $pr := sortBy "-MergedAt" $prs }}`
+func sortBy(field string, items interface{}) ([]interface{}, error) {
+	values, err := valuesOf(items)
+	if err != nil {
+		return nil, err
+	}
+	desc := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+	keys := make([]reflect.Value, len(values))
+	for i, v := range values {
+		if keys[i], err = fieldOf(v, field); err != nil {
+			return nil, err
+		}
+	}
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		c, e := compare(keys[idx[i]], keys[idx[j]])
+		if e != nil {
+			err = e
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot sort by '%s'", field)
+	}
+	result := make([]interface{}, len(idx))
+	for i, k := range idx {
+		result[i] = values[k].Interface()
+	}
+	return result, nil
+}
+
+// labelGroup the elements which have the same label
+type labelGroup struct {
+	// Label the name of the label, or an empty string for the elements without any label
+	Label string
+	Items []interface{}
+}
+
+// groupByLabel groups the elements of the given slice or map (pull requests or issues) by label, sorted by the name
+// of the label. Elements with several labels are in several groups, and elements without any label are in a last
+// group whose label is empty.
+// eg: `{{ range groupByLabel $issues }}{{ or .Label "other" }}: {{ len .Items }}{{ end }}`
+func groupByLabel(items interface{}) ([]labelGroup, error) {
+	values, err := valuesOf(items)
+	if err != nil {
+		return nil, err
+	}
+	byLabel := map[string][]interface{}{}
+	unlabelled := []interface{}{}
+	for _, v := range values {
+		f, err := fieldOf(v, "Labels")
+		if err != nil {
+			return nil, err
+		}
+		labels, ok := f.Interface().([]string)
+		if !ok {
+			return nil, errors.Errorf("cannot group by labels of type %s", f.Type())
+		}
+		if len(labels) == 0 {
+			unlabelled = append(unlabelled, v.Interface())
+		}
+		for _, l := range labels {
+			byLabel[l] = append(byLabel[l], v.Interface())
+		}
+	}
+	result := make([]labelGroup, 0, len(byLabel)+1)
+	for l, items := range byLabel {
+		result = append(result, labelGroup{Label: l, Items: items})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Label < result[j].Label
+	})
+	if len(unlabelled) > 0 {
+		result = append(result, labelGroup{Items: unlabelled})
+	}
+	return result, nil
+}
+
+// valuesOf returns the elements of the given slice, or the values of the given map sorted by key
+func valuesOf(items interface{}) ([]reflect.Value, error) {
+	v := reflect.ValueOf(items)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		result := make([]reflect.Value, v.Len())
+		for i := range result {
+			result[i] = v.Index(i)
+		}
+		return result, nil
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			c, _ := compare(keys[i], keys[j])
+			return c < 0
+		})
+		result := make([]reflect.Value, len(keys))
+		for i, k := range keys {
+			result[i] = v.MapIndex(k)
+		}
+		return result, nil
+	default:
+		return nil, errors.Errorf("expected a slice or a map, got %T", items)
+	}
+}
+
+// fieldOf returns the field with the given name in the given struct, or the value with the given key in the given map
+func fieldOf(v reflect.Value, name string) (reflect.Value, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	var f reflect.Value
+	switch v.Kind() {
+	case reflect.Struct:
+		f = v.FieldByName(name)
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			f = v.MapIndex(reflect.ValueOf(name))
+		}
+	}
+	if !f.IsValid() {
+		return f, errors.Errorf("no field '%s' in value of type %s", name, v.Type())
+	}
+	return f, nil
+}
+
+// compare compares the given values, which must be strings, numbers or times
+func compare(a, b reflect.Value) (int, error) {
+	for a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() {
+		return 0, errors.New("cannot compare nil values")
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			switch {
+			case ta.Before(tb):
+				return -1, nil
+			case ta.After(tb):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), nil
+	}
+	fa, err := toFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := toFloat(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case fa < fb:
+		return -1, nil
+	case fa > fb:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// toFloat converts the given number into a float
+func toFloat(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	default:
+		return 0, errors.Errorf("cannot compare values of kind %s", v.Kind())
+	}
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-changelog/report"
+)
+
+// numbersOf returns the numbers of the given pull requests, as returned by the template functions
+func numbersOf(t *testing.T, items []interface{}) []int64 {
+	t.Helper()
+	result := make([]int64, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case report.PullRequest:
+			result[i] = item.Number
+		case *report.PullRequest:
+			result[i] = item.Number
+		default:
+			t.Fatalf("unexpected item of type %T", item)
+		}
+	}
+	return result
+}
+
+func TestSortBy(t *testing.T) {
+	// given
+	day := func(d int) time.Time { return time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC) }
+	prs := []report.PullRequest{
+		{Number: 3, Title: "b", MergedAt: day(10)},
+		{Number: 1, Title: "c", MergedAt: day(12)},
+		{Number: 2, Title: "a", MergedAt: day(10)},
+	}
+	testcases := []struct {
+		field    string
+		items    interface{}
+		expected []int64
+	}{
+		{field: "Number", items: prs, expected: []int64{1, 2, 3}},
+		{field: "-Number", items: prs, expected: []int64{3, 2, 1}},
+		{field: "Title", items: prs, expected: []int64{2, 3, 1}},
+		// stable sort: the elements with the same merge time keep their order
+		{field: "MergedAt", items: prs, expected: []int64{3, 2, 1}},
+		{field: "-MergedAt", items: prs, expected: []int64{1, 3, 2}},
+		{field: "Number", items: []*report.PullRequest{&prs[0], &prs[1], &prs[2]}, expected: []int64{1, 2, 3}},
+		// values of a map, initially in the order of the keys
+		{field: "-MergedAt", items: map[string]report.PullRequest{"x": prs[0], "y": prs[1], "w": prs[2]}, expected: []int64{1, 2, 3}},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("%s %T", tc.field, tc.items), func(t *testing.T) {
+			// when
+			result, err := sortBy(tc.field, tc.items)
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := numbersOf(t, result); !reflect.DeepEqual(actual, tc.expected) {
+				t.Fatalf("unexpected order: %v (expected %v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSortByKeyOfMaps(t *testing.T) {
+	// given elements which are maps, as decoded from JSON
+	items := []interface{}{
+		map[string]interface{}{"number": 2.0, "title": "b"},
+		map[string]interface{}{"number": 10.0, "title": "a"},
+		map[string]interface{}{"number": 1.0, "title": "c"},
+	}
+	// when
+	result, err := sortBy("-number", items)
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, r := range result {
+		titles = append(titles, r.(map[string]interface{})["title"].(string))
+	}
+	if strings.Join(titles, ",") != "a,b,c" {
+		t.Fatalf("unexpected order: %v", titles)
+	}
+}
+
+func TestSortByInvalidArguments(t *testing.T) {
+	testcases := []struct {
+		name     string
+		field    string
+		items    interface{}
+		expected string
+	}{
+		{name: "unknown field", field: "Unknown", items: []report.PullRequest{{Number: 1}}, expected: "no field 'Unknown' in value of type report.PullRequest"},
+		{name: "not comparable", field: "Labels", items: []report.PullRequest{{Number: 1}, {Number: 2}}, expected: "cannot sort by 'Labels': cannot compare values of kind slice"},
+		{name: "not a list", field: "Number", items: report.PullRequest{}, expected: "expected a slice or a map, got report.PullRequest"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			_, err := sortBy(tc.field, tc.items)
+			// then
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGroupByLabel(t *testing.T) {
+	// given
+	prs := []report.PullRequest{
+		{Number: 1, Labels: []string{"enhancement"}},
+		{Number: 2},
+		{Number: 3, Labels: []string{"enhancement", "bug"}},
+		{Number: 4, Labels: []string{}},
+	}
+	// when
+	groups, err := groupByLabel(prs)
+	// then the groups are sorted by label, with the unlabelled elements last
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := []string{}
+	for _, g := range groups {
+		actual = append(actual, fmt.Sprintf("%s:%v", g.Label, numbersOf(t, g.Items)))
+	}
+	if expected := "bug:[3] enhancement:[1 3] :[2 4]"; strings.Join(actual, " ") != expected {
+		t.Fatalf("unexpected groups: %s (expected %s)", strings.Join(actual, " "), expected)
+	}
+}
+
+func TestGroupByLabelWithoutLabels(t *testing.T) {
+	// given
+	prs := map[string]report.PullRequest{"a": {Number: 1}}
+	// when
+	groups, err := groupByLabel(prs)
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Label != "" || len(groups[0].Items) != 1 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	// and elements without a 'Labels' field cannot be grouped
+	if _, err := groupByLabel([]report.Window{{}}); err == nil || err.Error() != "no field 'Labels' in value of type report.Window" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// given
+	day := func(d int) time.Time { return time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC) }
+	testcases := []struct {
+		a, b     interface{}
+		expected int
+	}{
+		{a: "a", b: "b", expected: -1},
+		{a: "b", b: "b", expected: 0},
+		{a: 10, b: 9, expected: 1},
+		{a: int64(2), b: 2.5, expected: -1},
+		{a: uint(3), b: float32(3), expected: 0},
+		{a: day(2), b: day(1), expected: 1},
+		{a: day(1), b: day(1), expected: 0},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("%v %v", tc.a, tc.b), func(t *testing.T) {
+			// when, with the values wrapped in interfaces, as in a `[]interface{}`
+			values := []interface{}{tc.a, tc.b}
+			c, err := compare(reflect.ValueOf(values).Index(0), reflect.ValueOf(values).Index(1))
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, c)
+			}
+		})
+	}
+}
+
+func TestCompareInvalidValues(t *testing.T) {
+	testcases := []struct {
+		name     string
+		a, b     interface{}
+		expected string
+	}{
+		{name: "string and number", a: "1", b: 1, expected: "cannot compare values of kind string"},
+		{name: "time and string", a: time.Now(), b: "now", expected: "cannot compare values of kind struct"},
+		{name: "nil", a: nil, b: 1, expected: "cannot compare nil values"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			values := []interface{}{tc.a, tc.b}
+			_, err := compare(reflect.ValueOf(values).Index(0), reflect.ValueOf(values).Index(1))
+			// then
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// writeTemplates writes the given templates in a new directory, and returns the path of the directory
+func writeTemplates(t *testing.T, templates map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range templates {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGenerateReportWithTemplateDirectory(t *testing.T) {
+	// given an entry point which includes the other templates of the directory
+	gh, zh := newTestServers(t)
+	seedReportData(gh, zh)
+	dir := writeTemplates(t, map[string]string{
+		"report.md": `{{ range .MergedPRs }}{{ template "repository.md" . }}{{ end }}`,
+		"repository.md": `## {{ .Repository }}
+{{ range sortBy "-MergedAt" .PullRequests }}{{ template "pull.md" . }}{{ end }}`,
+		"pull.md": `- #{{ .Number }} {{ escape .Title }} ({{ .MergedAt | date "Jan 2" }})
+`,
+	})
+	outputDir := filepath.Join(t.TempDir(), "out")
+	// when
+	_, err := runCommand(t, gh, zh, "report", "-r", "o/a,o/b", "--since", "2019-01-09", "--output", outputDir,
+		"--template", dir, "--generated-at", "2019-01-16T09:12:43Z")
+	// then the report is written in a file with the extension of the entry point, and rendered with the markdown escaping
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(outputDir, "changelog-2019-01-16.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "## o/a\n" +
+		"- #11 Fix the \\*bold\\* title (Jan 11)\n" +
+		"- #10 Add the cluster info endpoint (Jan 10)\n" +
+		"## o/b\n" +
+		"- #5 Bump the version (Jan 9)\n"
+	if string(out) != expected {
+		t.Fatalf("unexpected report:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestGenerateReportWithInvalidTemplateDirectory(t *testing.T) {
+	testcases := []struct {
+		name      string
+		templates map[string]string
+		expected  string
+	}{
+		{
+			name:      "no entry point",
+			templates: map[string]string{"main.md": "{{ .TotalPoints }}"},
+			expected:  "no entry point in template directory",
+		},
+		{
+			name:      "several entry points",
+			templates: map[string]string{"report.md": "{{ .TotalPoints }}", "report.html": "{{ .TotalPoints }}"},
+			expected:  "several entry points in template directory",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			gh, zh := newTestServers(t)
+			dir := writeTemplates(t, tc.templates)
+			// when
+			_, err := runCommand(t, gh, zh, "report", "-r", "o/a", "--since", "2019-01-09", "--output", "-", "--template", dir)
+			// then
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
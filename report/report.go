@@ -0,0 +1,110 @@
+// Package report defines the model of the report generated by the `report` command, as serialized
+// with the `--format json` and `--format yaml` flags. Tools which consume these outputs can decode them
+// into the `Report` type, after checking its `Version`.
+package report
+
+import "time"
+
+// Version the version of the structure of the report. It is incremented on every change which is not backward-compatible
+// (eg: a field is renamed or removed), but not when a field is added.
+const Version = 2
+
+// Report the report of the merged pull requests and of the issues in progress in a set of repositories
+type Report struct {
+	// Version the version of the structure of the report (see `Version`)
+	Version int `json:"version" yaml:"version"`
+	// GeneratedAt the time at which the report was generated
+	GeneratedAt time.Time `json:"generatedAt" yaml:"generatedAt"`
+	// Window the period during which the pull requests were merged
+	Window Window `json:"window" yaml:"window"`
+	// MergedPRs the pull requests merged during the period in each repository, sorted by name of repository.
+	// Repositories without any merged pull request are not included.
+	MergedPRs []RepositoryPullRequests `json:"mergedPullRequests" yaml:"mergedPullRequests"`
+	// InProgressIssues the open issues of the current milestone of each repository, per ZenHub pipeline,
+	// in the order of the pipelines given to the command
+	InProgressIssues []Pipeline `json:"inProgressIssues" yaml:"inProgressIssues"`
+	// TotalPoints the sum of the estimates of the issues in all pipelines
+	TotalPoints float64 `json:"totalPoints" yaml:"totalPoints"`
+	// Failures the repositories which could not be processed, and whose data is missing or incomplete in the report
+	Failures []Failure `json:"failures" yaml:"failures"`
+}
+
+// Window the period of a report. Both dates are inclusive.
+type Window struct {
+	// Since the first day of the period
+	Since time.Time `json:"since" yaml:"since"`
+	// Until the last day of the period, or nil if the period has no upper bound
+	Until *time.Time `json:"until,omitempty" yaml:"until,omitempty"`
+}
+
+// RepositoryPullRequests the pull requests merged in a repository
+type RepositoryPullRequests struct {
+	// Repository the name of the repository (eg: 'fabric8-services/fabric8-auth')
+	Repository string `json:"repository" yaml:"repository"`
+	// PullRequests the pull requests, sorted as given with the `--sort` flag of the command (by merge date, by default)
+	PullRequests []PullRequest `json:"pullRequests" yaml:"pullRequests"`
+}
+
+// PullRequest a merged pull request
+type PullRequest struct {
+	Number   int64     `json:"number" yaml:"number"`
+	Title    string    `json:"title" yaml:"title"`
+	URL      string    `json:"url" yaml:"url"`
+	MergedAt time.Time `json:"mergedAt" yaml:"mergedAt"`
+	// Author the login of the author of the pull request
+	Author string `json:"author,omitempty" yaml:"author,omitempty"`
+	// Labels the names of the labels of the pull request
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Pipeline the issues in a ZenHub pipeline
+type Pipeline struct {
+	// Name the name of the pipeline (eg: 'In Progress')
+	Name string `json:"name" yaml:"name"`
+	// Repositories the issues in the pipeline in each repository, sorted by name of repository.
+	// Repositories without any issue in the pipeline are not included.
+	Repositories []RepositoryIssues `json:"repositories" yaml:"repositories"`
+	// TotalPoints the sum of the estimates of all the issues in the pipeline
+	TotalPoints float64 `json:"totalPoints" yaml:"totalPoints"`
+}
+
+// RepositoryIssues the issues of a repository in a ZenHub pipeline
+type RepositoryIssues struct {
+	// Repository the name of the repository (eg: 'fabric8-services/fabric8-auth')
+	Repository string `json:"repository" yaml:"repository"`
+	// Issues the issues, sorted as given with the `--sort` flag of the command (by number, unless sorted by title, author or label)
+	Issues []Issue `json:"issues" yaml:"issues"`
+	// Points the sum of the estimates of the issues
+	Points float64 `json:"points" yaml:"points"`
+}
+
+// Issue an open issue of the current milestone of a repository
+type Issue struct {
+	Number int64  `json:"number" yaml:"number"`
+	Title  string `json:"title" yaml:"title"`
+	URL    string `json:"url" yaml:"url"`
+	// Author the login of the author of the issue
+	Author string `json:"author,omitempty" yaml:"author,omitempty"`
+	// Assignees the logins of the users assigned to the issue
+	Assignees []string `json:"assignees" yaml:"assignees"`
+	// Labels the names of the labels of the issue
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Pipeline the name of the ZenHub pipeline of the issue
+	Pipeline string `json:"pipeline" yaml:"pipeline"`
+	// Estimate the ZenHub estimate of the issue, in story points (0 if not estimated)
+	Estimate float64 `json:"estimate,omitempty" yaml:"estimate,omitempty"`
+	// Epic the parent epic of the issue on ZenHub, if any
+	Epic *Epic `json:"epic,omitempty" yaml:"epic,omitempty"`
+}
+
+// Epic the parent epic of an issue on ZenHub
+type Epic struct {
+	Number int64  `json:"number" yaml:"number"`
+	URL    string `json:"url" yaml:"url"`
+}
+
+// Failure a repository which could not be processed, and the reason why
+type Failure struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Reason     string `json:"reason" yaml:"reason"`
+}
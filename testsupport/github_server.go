@@ -0,0 +1,730 @@
+package testsupport
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubServer an in-process fake of the GitHub Rest v3 endpoints for milestones and issues, and of the
+// GraphQL queries used by the commands (repository discovery and report), backed by an in-memory model which can be seeded and inspected.
+// The model must not be seeded or inspected while a command is running.
+type GitHubServer struct {
+	*httptest.Server
+	lock         sync.Mutex
+	repositories map[string]*Repository
+}
+
+// Repository a repository in the model of the fake GitHub server
+type Repository struct {
+	Owner        string
+	Name         string
+	DatabaseID   int64
+	IsArchived   bool
+	IsFork       bool
+	IsPrivate    bool
+	Topics       []string
+	Milestones   []*Milestone
+	Issues       []*Issue
+	PullRequests []*PullRequest
+}
+
+// Milestone a milestone in a repository
+type Milestone struct {
+	Number int64
+	Title  string
+	// State 'open' or 'closed'
+	State string
+	DueOn time.Time
+}
+
+// Issue an issue in a repository
+type Issue struct {
+	Number int64
+	Title  string
+	// State 'open' or 'closed'
+	State string
+	// Milestone the number of the milestone of the issue, or 0 if none
+	Milestone int64
+	// Author the login of the author of the issue
+	Author string
+	// Assignees the logins of the users assigned to the issue
+	Assignees []string
+	// Labels the names of the labels of the issue
+	Labels []string
+}
+
+// PullRequest a pull request in a repository
+type PullRequest struct {
+	Number int64
+	Title  string
+	// State 'OPEN', 'CLOSED' or 'MERGED'
+	State    string
+	MergedAt time.Time
+	// Author the login of the author of the pull request
+	Author string
+	// Labels the names of the labels of the pull request
+	Labels []string
+}
+
+// NewGitHubServer starts a new fake GitHub server, which must be closed after use
+func NewGitHubServer() *GitHubServer {
+	s := &GitHubServer{
+		repositories: map[string]*Repository{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/", s.handleRest)
+	mux.HandleFunc("/repositories/", s.handleRest)
+	mux.HandleFunc("/graphql", s.handleGraphql)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// GraphqlURL returns the URL of the GraphQL endpoint of the server
+func (s *GitHubServer) GraphqlURL() string {
+	return s.URL + "/graphql"
+}
+
+// AddRepository adds a repository with the given full name (eg: 'fabric8-services/fabric8-auth') and database ID
+func (s *GitHubServer) AddRepository(fullname string, databaseID int64) *Repository {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	remote := strings.SplitN(fullname, "/", 2)
+	r := &Repository{
+		Owner:      remote[0],
+		Name:       remote[1],
+		DatabaseID: databaseID,
+	}
+	s.repositories[fullname] = r
+	return r
+}
+
+// Repository returns the repository with the given full name, or nil if it does not exist
+func (s *GitHubServer) Repository(fullname string) *Repository {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.repositories[fullname]
+}
+
+// AddMilestone adds an open milestone with the given title and due date, and returns it
+func (r *Repository) AddMilestone(title string, dueOn time.Time) *Milestone {
+	m := &Milestone{
+		Number: int64(len(r.Milestones) + 1),
+		Title:  title,
+		State:  "open",
+		DueOn:  dueOn,
+	}
+	r.Milestones = append(r.Milestones, m)
+	return m
+}
+
+// AddIssue adds an open issue in the given milestone (or in no milestone if nil), and returns it
+func (r *Repository) AddIssue(number int64, title string, milestone *Milestone) *Issue {
+	i := &Issue{
+		Number: number,
+		Title:  title,
+		State:  "open",
+	}
+	if milestone != nil {
+		i.Milestone = milestone.Number
+	}
+	r.Issues = append(r.Issues, i)
+	return i
+}
+
+// AddMergedPullRequest adds a pull request merged at the given time, and returns it
+func (r *Repository) AddMergedPullRequest(number int64, title string, mergedAt time.Time) *PullRequest {
+	pr := &PullRequest{
+		Number:   number,
+		Title:    title,
+		State:    "MERGED",
+		MergedAt: mergedAt,
+	}
+	r.PullRequests = append(r.PullRequests, pr)
+	return pr
+}
+
+// MilestoneByTitle returns the milestone with the given title, or nil if none matched
+func (r *Repository) MilestoneByTitle(title string) *Milestone {
+	for _, m := range r.Milestones {
+		if m.Title == title {
+			return m
+		}
+	}
+	return nil
+}
+
+// IssuesInMilestone returns the issues in the given milestone
+func (r *Repository) IssuesInMilestone(milestone *Milestone) []*Issue {
+	result := []*Issue{}
+	for _, i := range r.Issues {
+		if milestone != nil && i.Milestone == milestone.Number {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// ------------------------------------------------------------
+// Rest v3 API
+// ------------------------------------------------------------
+
+func (s *GitHubServer) handleRest(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// path: /repos/:owner/:name/:kind[/:number] or /repositories/:id/:kind[/:number] (as in the `Link` headers)
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if segments[0] == "repositories" && len(segments) > 1 {
+		id := segments[1]
+		segments = append([]string{"repos", "", ""}, segments[2:]...)
+		for _, r := range s.repositories {
+			if strconv.FormatInt(r.DatabaseID, 10) == id {
+				segments[1], segments[2] = r.Owner, r.Name
+			}
+		}
+	}
+	if len(segments) < 4 {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	r, found := s.repositories[segments[1]+"/"+segments[2]]
+	if !found {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	var number int64
+	if len(segments) > 4 {
+		n, err := strconv.ParseInt(segments[4], 10, 64)
+		if err != nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		number = n
+	}
+	switch {
+	case segments[3] == "milestones" && number == 0 && req.Method == http.MethodGet:
+		s.listMilestones(w, req, r)
+	case segments[3] == "milestones" && number == 0 && req.Method == http.MethodPost:
+		s.createMilestone(w, req, r)
+	case segments[3] == "milestones" && number != 0 && req.Method == http.MethodPatch:
+		s.updateMilestone(w, req, r, number)
+	case segments[3] == "issues" && number == 0 && req.Method == http.MethodGet:
+		s.listIssues(w, req, r)
+	case segments[3] == "issues" && number != 0 && req.Method == http.MethodPatch:
+		s.updateIssue(w, req, r, number)
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *GitHubServer) listMilestones(w http.ResponseWriter, req *http.Request, r *Repository) {
+	state := req.URL.Query().Get("state")
+	items := []interface{}{}
+	// most recent milestones first, as with `direction=desc`
+	for i := len(r.Milestones) - 1; i >= 0; i-- {
+		m := r.Milestones[i]
+		if state == "all" || m.State == state || (state == "" && m.State == "open") {
+			items = append(items, s.milestoneJSON(r, m))
+		}
+	}
+	writePage(w, req, r, items)
+}
+
+func (s *GitHubServer) createMilestone(w http.ResponseWriter, req *http.Request, r *Repository) {
+	payload := struct {
+		Title string    `json:"title"`
+		State string    `json:"state"`
+		DueOn time.Time `json:"due_on"`
+	}{}
+	if err := readJSON(req, &payload); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if r.MilestoneByTitle(payload.Title) != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "Validation Failed",
+			"errors": []map[string]string{
+				{"resource": "Milestone", "code": "already_exists", "field": "title"},
+			},
+		})
+		return
+	}
+	m := r.AddMilestone(payload.Title, payload.DueOn)
+	writeJSON(w, http.StatusCreated, s.milestoneJSON(r, m))
+}
+
+func (s *GitHubServer) updateMilestone(w http.ResponseWriter, req *http.Request, r *Repository, number int64) {
+	payload := struct {
+		State string `json:"state"`
+	}{}
+	if err := readJSON(req, &payload); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	for _, m := range r.Milestones {
+		if m.Number == number {
+			if payload.State != "" {
+				m.State = payload.State
+			}
+			writeJSON(w, http.StatusOK, s.milestoneJSON(r, m))
+			return
+		}
+	}
+	writeMessage(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *GitHubServer) listIssues(w http.ResponseWriter, req *http.Request, r *Repository) {
+	state := req.URL.Query().Get("state")
+	milestone := req.URL.Query().Get("milestone")
+	items := []interface{}{}
+	for _, i := range r.Issues {
+		if state != "all" && i.State != state && !(state == "" && i.State == "open") {
+			continue
+		}
+		if milestone != "" && milestone != strconv.FormatInt(i.Milestone, 10) {
+			continue
+		}
+		items = append(items, s.issueJSON(r, i))
+	}
+	writePage(w, req, r, items)
+}
+
+func (s *GitHubServer) updateIssue(w http.ResponseWriter, req *http.Request, r *Repository, number int64) {
+	payload := struct {
+		Milestone *int64 `json:"milestone"`
+	}{}
+	if err := readJSON(req, &payload); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	for _, i := range r.Issues {
+		if i.Number == number {
+			if payload.Milestone != nil {
+				i.Milestone = *payload.Milestone
+			}
+			writeJSON(w, http.StatusOK, s.issueJSON(r, i))
+			return
+		}
+	}
+	writeMessage(w, http.StatusNotFound, "Not Found")
+}
+
+func (s *GitHubServer) milestoneJSON(r *Repository, m *Milestone) map[string]interface{} {
+	return map[string]interface{}{
+		"number": m.Number,
+		"title":  m.Title,
+		"state":  m.State,
+		"due_on": m.DueOn.Format(time.RFC3339),
+		"url":    fmt.Sprintf("%s/repos/%s/%s/milestones/%d", s.URL, r.Owner, r.Name, m.Number),
+	}
+}
+
+func (s *GitHubServer) issueJSON(r *Repository, i *Issue) map[string]interface{} {
+	result := map[string]interface{}{
+		"number": i.Number,
+		"title":  i.Title,
+		"state":  i.State,
+		"url":    fmt.Sprintf("%s/repos/%s/%s/issues/%d", s.URL, r.Owner, r.Name, i.Number),
+	}
+	for _, m := range r.Milestones {
+		if m.Number == i.Milestone {
+			result["milestone"] = s.milestoneJSON(r, m)
+		}
+	}
+	return result
+}
+
+// writePage writes the page of items given the `page` and `per_page` query params, along with the `Link` header,
+// in which the repository is identified by its ID (as GitHub does)
+func writePage(w http.ResponseWriter, req *http.Request, r *Repository, items []interface{}) {
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(req.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	}
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	if end < len(items) {
+		next := *req.URL
+		next.Path = strings.Replace(next.Path, fmt.Sprintf("/repos/%s/%s/", r.Owner, r.Name), fmt.Sprintf("/repositories/%d/", r.DatabaseID), 1)
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, req.Host, next.RequestURI()))
+	}
+	writeJSON(w, http.StatusOK, items[start:end])
+}
+
+// ------------------------------------------------------------
+// GraphQL API
+// ------------------------------------------------------------
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func (s *GitHubServer) handleGraphql(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	request := graphqlRequest{}
+	if err := readJSON(req, &request); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	switch request.OperationName {
+	case "FetchReportData":
+		s.fetchReportData(w, request)
+	case "ListOrganizationRepositories":
+		s.listOrganizationRepositories(w, request)
+	default:
+		writeGraphqlErrors(w, map[string]interface{}{
+			"message": fmt.Sprintf("unsupported operation '%s'", request.OperationName),
+		})
+	}
+}
+
+var (
+	// eg: `r0: repository(owner: $owner0, name: $name0) {`
+	repositoryField = regexp.MustCompile(`(\w+): repository\(owner: \$(\w+), name: \$(\w+)\)`)
+	// eg: `issues(states: OPEN, first: 100, orderBy: {field: UPDATED_AT, direction: DESC}, after: $issuesAfter0)`
+	afterArgument = regexp.MustCompile(`after: \$(\w+)`)
+	// eg: `p0: search(type: ISSUE, query: $query0, first: 100, after: $pullsAfter0)`
+	searchField = regexp.MustCompile(`(\w+): search\(type: ISSUE, query: \$(\w+), first: \d+, after: \$(\w+)\)`)
+)
+
+// fetchReportData supports the aliased `repository` and `search` fields of the batched query of the `report` command,
+// with the variables of each field
+func (s *GitHubServer) fetchReportData(w http.ResponseWriter, request graphqlRequest) {
+	data := map[string]interface{}{
+		"rateLimit": map[string]interface{}{
+			"cost":      1,
+			"remaining": 4999,
+		},
+	}
+	errs := []map[string]interface{}{}
+	for _, m := range repositoryField.FindAllStringSubmatchIndex(request.Query, -1) {
+		alias := request.Query[m[2]:m[3]]
+		owner := stringVar(request, request.Query[m[4]:m[5]])
+		name := stringVar(request, request.Query[m[6]:m[7]])
+		// the cursor of the issues is the first `after` argument in the field
+		after := ""
+		if a := afterArgument.FindStringSubmatch(request.Query[m[1]:]); a != nil {
+			after = stringVar(request, a[1])
+		}
+		r, found := s.repositories[owner+"/"+name]
+		if !found {
+			data[alias] = nil
+			errs = append(errs, map[string]interface{}{
+				"type":    "NOT_FOUND",
+				"path":    []string{alias},
+				"message": fmt.Sprintf("Could not resolve to a Repository with the name '%s'.", name),
+			})
+			continue
+		}
+		data[alias] = s.milestoneIssues(r, after)
+	}
+	for _, m := range searchField.FindAllStringSubmatch(request.Query, -1) {
+		alias, query, after := m[1], stringVar(request, m[2]), stringVar(request, m[3])
+		search, found := s.searchMergedPullRequests(query, after)
+		if !found {
+			data[alias] = nil
+			errs = append(errs, map[string]interface{}{
+				"type":    "INVALID",
+				"path":    []string{alias},
+				"message": "The listed users and repositories cannot be searched either because the resources do not exist or you do not have permission to view them.",
+			})
+			continue
+		}
+		data[alias] = search
+	}
+	response := map[string]interface{}{
+		"data": data,
+	}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// searchMergedPullRequests supports the `repo:`, `is:pr`, `is:merged` and `merged:` qualifiers of the search query,
+// with `merged:>=DATE`, `merged:DATE..DATE` and `merged:DATE` date ranges. Returns false if the repository does not exist.
+func (s *GitHubServer) searchMergedPullRequests(query, after string) (map[string]interface{}, bool) {
+	var r *Repository
+	var from, to time.Time
+	for _, q := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(q, "repo:"):
+			r = s.repositories[strings.TrimPrefix(q, "repo:")]
+		case strings.HasPrefix(q, "merged:>="):
+			from, _ = time.Parse("2006-01-02", strings.TrimPrefix(q, "merged:>="))
+		case strings.HasPrefix(q, "merged:"):
+			dates := strings.SplitN(strings.TrimPrefix(q, "merged:"), "..", 2)
+			from, _ = time.Parse("2006-01-02", dates[0])
+			to, _ = time.Parse("2006-01-02", dates[len(dates)-1])
+		}
+	}
+	if r == nil {
+		return nil, false
+	}
+	pulls := []*PullRequest{}
+	for _, pr := range r.PullRequests {
+		// the upper bound is inclusive, until the end of the day
+		if pr.State == "MERGED" && !pr.MergedAt.Before(from) && (to.IsZero() || pr.MergedAt.Before(to.AddDate(0, 0, 1))) {
+			pulls = append(pulls, pr)
+		}
+	}
+	sort.SliceStable(pulls, func(i, j int) bool {
+		return pulls[i].MergedAt.After(pulls[j].MergedAt)
+	})
+	// `first` items `after` the cursor (100 in the query)
+	start := 0
+	if after != "" {
+		start = decodeCursor(after) + 1
+	}
+	end := len(pulls)
+	if start+100 < end {
+		end = start + 100
+	}
+	nodes := []map[string]interface{}{}
+	for _, pr := range pulls[start:end] {
+		nodes = append(nodes, map[string]interface{}{
+			"number":    pr.Number,
+			"title":     pr.Title,
+			"mergedAt":  formatTime(pr.MergedAt),
+			"permalink": fmt.Sprintf("%s/%s/%s/pull/%d", s.URL, r.Owner, r.Name, pr.Number),
+			"author":    authorJSON(pr.Author),
+			"labels":    labelsJSON(pr.Labels),
+		})
+	}
+	return map[string]interface{}{
+		"issueCount": len(pulls),
+		"pageInfo": map[string]interface{}{
+			"endCursor":   encodeCursor(end - 1),
+			"hasNextPage": end < len(pulls),
+		},
+		"nodes": nodes,
+	}, true
+}
+
+// milestoneIssues returns the open issues of the current milestone of the given repository, `after` the given cursor
+func (s *GitHubServer) milestoneIssues(r *Repository, after string) map[string]interface{} {
+	// milestones(states:OPEN, first:1, orderBy:{field:DUE_DATE, direction:ASC})
+	var current *Milestone
+	for _, m := range r.Milestones {
+		if m.State == "open" && (current == nil || m.DueOn.Before(current.DueOn)) {
+			current = m
+		}
+	}
+	milestones := []map[string]interface{}{}
+	if current != nil {
+		issues := []*Issue{}
+		for _, i := range r.IssuesInMilestone(current) {
+			if i.State == "open" {
+				issues = append(issues, i)
+			}
+		}
+		// `first` items `after` the cursor (100 in the query)
+		start := 0
+		if after != "" {
+			start = decodeCursor(after) + 1
+		}
+		end := len(issues)
+		if start+100 < end {
+			end = start + 100
+		}
+		nodes := []map[string]interface{}{}
+		for _, i := range issues[start:end] {
+			assignees := []map[string]string{}
+			for _, a := range i.Assignees {
+				assignees = append(assignees, map[string]string{"login": a})
+			}
+			nodes = append(nodes, map[string]interface{}{
+				"number":    i.Number,
+				"title":     i.Title,
+				"url":       fmt.Sprintf("%s/%s/%s/issues/%d", s.URL, r.Owner, r.Name, i.Number),
+				"assignees": map[string]interface{}{"nodes": assignees},
+				"author":    authorJSON(i.Author),
+				"labels":    labelsJSON(i.Labels),
+			})
+		}
+		milestones = append(milestones, map[string]interface{}{
+			"title": current.Title,
+			"issues": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"endCursor":   encodeCursor(end - 1),
+					"hasNextPage": end < len(issues),
+				},
+				"nodes": nodes,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"databaseId": r.DatabaseID,
+		"milestones": map[string]interface{}{
+			"nodes": milestones,
+		},
+	}
+}
+
+// authorJSON returns the `author { login }` field with the given login, or null if the login is empty (deleted account)
+func authorJSON(login string) map[string]interface{} {
+	if login == "" {
+		return nil
+	}
+	return map[string]interface{}{"login": login}
+}
+
+// labelsJSON returns the `labels { nodes { name } }` connection with the given labels
+func labelsJSON(labels []string) map[string]interface{} {
+	nodes := []map[string]string{}
+	for _, l := range labels {
+		nodes = append(nodes, map[string]string{"name": l})
+	}
+	return map[string]interface{}{"nodes": nodes}
+}
+
+func (s *GitHubServer) listOrganizationRepositories(w http.ResponseWriter, request graphqlRequest) {
+	org := stringVar(request, "org")
+	repositories := []*Repository{}
+	for _, r := range s.repositories {
+		if r.Owner == org {
+			repositories = append(repositories, r)
+		}
+	}
+	if len(repositories) == 0 {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"organization": nil},
+			"errors": []map[string]interface{}{
+				{
+					"type":    "NOT_FOUND",
+					"path":    []string{"organization"},
+					"message": fmt.Sprintf("Could not resolve to an Organization with the login of '%s'.", org),
+				},
+			},
+		})
+		return
+	}
+	// orderBy:{field:NAME, direction:ASC}
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Name < repositories[j].Name
+	})
+	// `first` items `after` the cursor (100 in the query)
+	start := 0
+	if after := stringVar(request, "after"); after != "" {
+		start = decodeCursor(after) + 1
+	}
+	end := len(repositories)
+	if start+100 < end {
+		end = start + 100
+	}
+	nodes := []map[string]interface{}{}
+	for _, r := range repositories[start:end] {
+		topics := []map[string]interface{}{}
+		for _, t := range r.Topics {
+			topics = append(topics, map[string]interface{}{"topic": map[string]string{"name": t}})
+		}
+		nodes = append(nodes, map[string]interface{}{
+			"nameWithOwner":    r.Owner + "/" + r.Name,
+			"name":             r.Name,
+			"isArchived":       r.IsArchived,
+			"isFork":           r.IsFork,
+			"isPrivate":        r.IsPrivate,
+			"repositoryTopics": map[string]interface{}{"nodes": topics},
+		})
+	}
+	writeGraphqlData(w, map[string]interface{}{
+		"organization": map[string]interface{}{
+			"repositories": map[string]interface{}{
+				"pageInfo": map[string]interface{}{
+					"endCursor":   encodeCursor(end - 1),
+					"hasNextPage": end < len(repositories),
+				},
+				"nodes": nodes,
+			},
+		},
+	})
+}
+
+func stringVar(request graphqlRequest, name string) string {
+	if v, ok := request.Variables[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func encodeCursor(index int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("cursor:%d", index)))
+}
+
+func decodeCursor(cursor string) int {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(string(data), "cursor:"))
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+func formatTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+func writeGraphqlData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": data,
+	})
+}
+
+func writeGraphqlErrors(w http.ResponseWriter, errs ...map[string]interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errors": errs,
+	})
+}
+
+// ------------------------------------------------------------
+// utilities
+// ------------------------------------------------------------
+
+func readJSON(req *http.Request, result interface{}) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,269 @@
+package testsupport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ZenHubServer an in-process fake of the ZenHub API endpoints (issue data, epics and boards) used by
+// the `report` command, backed by an in-memory model which can be seeded and inspected.
+// The model must not be seeded or inspected while a command is running.
+type ZenHubServer struct {
+	*httptest.Server
+	lock sync.Mutex
+	// the current pipelines of the issues, indexed by repository ID and issue number
+	pipelines map[int64]map[int64]string
+	// the estimates of the issues, indexed by repository ID and issue number
+	estimates map[int64]map[int64]float64
+	// the issues of the epics, indexed by repository ID and epic number
+	epics map[int64]map[int64][]int64
+	// the repositories which are not in the workspace, by ID
+	outOfWorkspace map[int64]bool
+	// the issues which are not on the boards, indexed by repository ID and issue number
+	offBoard map[int64]map[int64]bool
+	// the number of requests received by the server
+	requests int
+	// Pipelines the pipelines of the boards, in order
+	Pipelines []string
+}
+
+// NewZenHubServer starts a new fake ZenHub server, which must be closed after use
+func NewZenHubServer() *ZenHubServer {
+	s := &ZenHubServer{
+		pipelines:      map[int64]map[int64]string{},
+		estimates:      map[int64]map[int64]float64{},
+		epics:          map[int64]map[int64][]int64{},
+		outOfWorkspace: map[int64]bool{},
+		offBoard:       map[int64]map[int64]bool{},
+		Pipelines:      []string{"New Issues", "Backlog", "In Progress", "Review/QA", "Done"},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/p1/repositories/", s.handleRepository)
+	mux.HandleFunc("/p1/workspaces/", s.handleBoard)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.lock.Lock()
+		s.requests++
+		s.lock.Unlock()
+		mux.ServeHTTP(w, req)
+	}))
+	return s
+}
+
+// RequestCount returns the number of requests received by the server
+func (s *ZenHubServer) RequestCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests
+}
+
+// MoveIssue moves the given issue to the given pipeline
+func (s *ZenHubServer) MoveIssue(repoID, number int64, pipeline string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.pipelines[repoID]; !found {
+		s.pipelines[repoID] = map[int64]string{}
+	}
+	s.pipelines[repoID][number] = pipeline
+}
+
+// Pipeline returns the current pipeline of the given issue, or an empty string if it was never moved
+func (s *ZenHubServer) Pipeline(repoID, number int64) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pipelines[repoID][number]
+}
+
+// SetEstimate sets the estimate of the given issue, in story points
+func (s *ZenHubServer) SetEstimate(repoID, number int64, estimate float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.estimates[repoID]; !found {
+		s.estimates[repoID] = map[int64]float64{}
+	}
+	s.estimates[repoID][number] = estimate
+}
+
+// AddEpic converts the given issue into an epic which contains the given issues of the same repository
+func (s *ZenHubServer) AddEpic(repoID, number int64, issues ...int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.epics[repoID]; !found {
+		s.epics[repoID] = map[int64][]int64{}
+	}
+	s.epics[repoID][number] = issues
+}
+
+// RemoveFromWorkspace removes the given repository from the workspace, so that its board is not found
+func (s *ZenHubServer) RemoveFromWorkspace(repoID int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.outOfWorkspace[repoID] = true
+}
+
+// RemoveFromBoard removes the given issue from the board of its repository, while keeping its data
+func (s *ZenHubServer) RemoveFromBoard(repoID, number int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.offBoard[repoID]; !found {
+		s.offBoard[repoID] = map[int64]bool{}
+	}
+	s.offBoard[repoID][number] = true
+}
+
+func (s *ZenHubServer) handleRepository(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// paths:
+	// /p1/repositories/:repo_id/issues/:issue_number
+	// /p1/repositories/:repo_id/epics
+	// /p1/repositories/:repo_id/epics/:issue_number
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) < 4 || req.Method != http.MethodGet {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	repoID, err := strconv.ParseInt(segments[2], 10, 64)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	var number int64
+	if len(segments) > 4 {
+		if number, err = strconv.ParseInt(segments[4], 10, 64); err != nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+	}
+	switch {
+	case segments[3] == "issues" && len(segments) == 5:
+		s.issue(w, repoID, number)
+	case segments[3] == "epics" && len(segments) == 4:
+		s.listEpics(w, repoID)
+	case segments[3] == "epics" && len(segments) == 5:
+		s.epic(w, repoID, number)
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *ZenHubServer) issue(w http.ResponseWriter, repoID, number int64) {
+	_, isEpic := s.epics[repoID][number]
+	issue := map[string]interface{}{
+		"plus_ones": []interface{}{},
+		"pipeline":  map[string]string{"name": s.pipelines[repoID][number]},
+		"is_epic":   isEpic,
+	}
+	if estimate, found := s.estimates[repoID][number]; found {
+		issue["estimate"] = map[string]float64{"value": estimate}
+	}
+	writeJSON(w, http.StatusOK, issue)
+}
+
+func (s *ZenHubServer) listEpics(w http.ResponseWriter, repoID int64) {
+	epics := []map[string]interface{}{}
+	numbers := []int64{}
+	for number := range s.epics[repoID] {
+		numbers = append(numbers, number)
+	}
+	for _, number := range sortInt64s(numbers) {
+		epics = append(epics, map[string]interface{}{
+			"issue_number": number,
+			"repo_id":      repoID,
+			"issue_url":    fmt.Sprintf("https://github.com/repositories/%d/issues/%d", repoID, number),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"epic_issues": epics,
+	})
+}
+
+func (s *ZenHubServer) epic(w http.ResponseWriter, repoID, number int64) {
+	children, found := s.epics[repoID][number]
+	if !found {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	issues := []map[string]interface{}{}
+	total := 0.0
+	for _, c := range children {
+		issue := map[string]interface{}{
+			"issue_number": c,
+			"repo_id":      repoID,
+			"is_epic":      false,
+		}
+		if estimate, found := s.estimates[repoID][c]; found {
+			issue["estimate"] = map[string]float64{"value": estimate}
+			total += estimate
+		}
+		issues = append(issues, issue)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_epic_estimates": map[string]float64{"value": total},
+		"issues":               issues,
+	})
+}
+
+func (s *ZenHubServer) handleBoard(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// path: /p1/workspaces/:workspace_id/repositories/:repo_id/board
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) != 6 || segments[3] != "repositories" || segments[5] != "board" || req.Method != http.MethodGet {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	repoID, err := strconv.ParseInt(segments[4], 10, 64)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if s.outOfWorkspace[repoID] {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	numbers := []int64{}
+	for number := range s.pipelines[repoID] {
+		if !s.offBoard[repoID][number] {
+			numbers = append(numbers, number)
+		}
+	}
+	sortInt64s(numbers)
+	pipelines := []map[string]interface{}{}
+	for i, name := range s.Pipelines {
+		issues := []map[string]interface{}{}
+		for _, number := range numbers {
+			if s.pipelines[repoID][number] != name {
+				continue
+			}
+			_, isEpic := s.epics[repoID][number]
+			issue := map[string]interface{}{
+				"issue_number": number,
+				"position":     len(issues),
+				"is_epic":      isEpic,
+			}
+			if estimate, found := s.estimates[repoID][number]; found {
+				issue["estimate"] = map[string]float64{"value": estimate}
+			}
+			issues = append(issues, issue)
+		}
+		pipelines = append(pipelines, map[string]interface{}{
+			"id":     strconv.Itoa(i + 1),
+			"name":   name,
+			"issues": issues,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelines": pipelines,
+	})
+}
+
+// sortInt64s sorts the given numbers in ascending order, and returns them
+func sortInt64s(numbers []int64) []int64 {
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}